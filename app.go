@@ -1,16 +1,23 @@
 package main
 
 import (
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"strings"
+	"time"
 
 	cli "gopkg.in/urfave/cli.v2"
 
 	"github.com/krostar/nebulo-golib/log"
+	"github.com/krostar/nebulo-golib/tools/cert"
+	"github.com/krostar/nebulo-server/certificate/crl"
+	"github.com/krostar/nebulo-server/certificate/responder"
 	"github.com/krostar/nebulo-server/config"
+	"github.com/krostar/nebulo-server/message/purge"
+	"github.com/krostar/nebulo-server/migration"
 	"github.com/krostar/nebulo-server/router"
 	"github.com/krostar/nebulo-server/router/handler"
 )
@@ -103,23 +110,96 @@ func main() {
 						Name:        "tls-clients-ca-key-pwd",
 						Usage:       "password/passphrase used with --tls-clients-ca-key",
 						Destination: &config.CLI.Run.TLS.ClientsCA.KeyPassword,
+					}, &cli.IntFlag{
+						Name:        "tls-clients-ca-crl-interval",
+						Usage:       "number of seconds between two generations of the certificate revocation list",
+						DefaultText: "3600",
+						Destination: &config.CLI.Run.TLS.ClientsCA.CRLInterval,
+					}, &cli.StringFlag{
+						Name:        "tls-ocsp-crt",
+						Usage:       "delegated OCSP responder certificate issued by --tls-clients-ca, the responder is disabled without it",
+						Destination: &config.CLI.Run.TLS.ClientsCA.OCSP.Cert,
+					}, &cli.StringFlag{
+						Name:        "tls-ocsp-key",
+						Usage:       "delegated OCSP responder key used with --tls-ocsp-crt",
+						Destination: &config.CLI.Run.TLS.ClientsCA.OCSP.Key,
+					}, &cli.StringFlag{
+						Name:        "tls-ocsp-key-pwd",
+						Usage:       "password/passphrase used with --tls-ocsp-key",
+						Destination: &config.CLI.Run.TLS.ClientsCA.OCSP.KeyPassword,
+					}, &cli.IntFlag{
+						Name:        "tls-ocsp-cache-duration",
+						Usage:       "number of seconds OCSP responses are valid and cached",
+						DefaultText: "3600",
+						Destination: &config.CLI.Run.TLS.ClientsCA.OCSP.CacheDuration,
 					}, &cli.StringFlag{
 						Name:        "provider",
-						Usage:       "* database type to use to provide users and messages (sqlite)",
+						Usage:       "* database type to use to provide users and messages (sqlite, mysql, postgres, memory)",
 						Destination: &config.CLI.Run.Provider.Type,
+					}, &cli.IntFlag{
+						Name:        "messages-purge-interval",
+						Usage:       "number of seconds between two purges of the expired messages, negative to disable the purge",
+						DefaultText: "60",
+						Destination: &config.CLI.Run.Messages.PurgeInterval,
 					}, &cli.BoolFlag{
-						Name:        "provider-createtables",
-						Usage:       "create tables if not exists",
-						DefaultText: "false",
-						Destination: &config.CLI.Run.Provider.CreateTablesIfNotExists,
-					}, &cli.BoolFlag{
-						Name:        "provider-droptables",
-						Usage:       "drop tables if not exists - only available in dev environment",
+						Name:        "messages-delete-after-delivery",
+						Usage:       "delete the receiver copy of a message once he acknowledged it",
 						DefaultText: "false",
-						Destination: &config.CLI.Run.Provider.DropTablesIfExists,
+						Destination: &config.CLI.Run.Messages.DeleteAfterDelivery,
+					}, &cli.StringFlag{
+						Name:        "attachments-storage",
+						Usage:       "storage to use to store attachments contents (local)",
+						DefaultText: "local",
+						Destination: &config.CLI.Run.Attachments.Storage,
+					}, &cli.StringFlag{
+						Name:        "attachments-directory",
+						Usage:       "directory where attachments are stored with the local storage",
+						DefaultText: "attachments",
+						Destination: &config.CLI.Run.Attachments.Local.Directory,
 					},
 				}, Before: beforeCommandWhoNeedMergeConfiguration,
 				Action: commandRun,
+			}, &cli.Command{ // migrate command, apply or revert the database schema migrations
+				Name:  "migrate",
+				Usage: "manage the database schema",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "provider",
+						Usage:       "* database type whose schema is managed (sqlite, mysql, postgres)",
+						Destination: &config.CLI.Run.Provider.Type,
+					},
+				}, Subcommands: []*cli.Command{
+					&cli.Command{
+						Name:  "up",
+						Usage: "apply the migrations up to a version, the tables created before the migrations existed are adopted",
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:        "to",
+								Usage:       "version to migrate to",
+								Value:       -1,
+								DefaultText: "latest",
+							},
+						}, Before: beforeCommandWhoNeedDatabase,
+						Action: commandMigrateUp,
+					}, &cli.Command{
+						Name:  "down",
+						Usage: "revert the migrations down to a version",
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:        "to",
+								Usage:       "version to migrate to",
+								Value:       -1,
+								DefaultText: "previous version",
+							},
+						}, Before: beforeCommandWhoNeedDatabase,
+						Action: commandMigrateDown,
+					}, &cli.Command{
+						Name:   "status",
+						Usage:  "display the applied and pending migrations",
+						Before: beforeCommandWhoNeedDatabase,
+						Action: commandMigrateStatus,
+					},
+				},
 			}, &cli.Command{ // config-gen command, generate the configuration
 				Name:  "config-gen",
 				Usage: "generate a configuration file and quit",
@@ -168,7 +248,44 @@ func beforeCommandWhoNeedMergeConfiguration(c *cli.Context) (err error) {
 	return nil
 }
 
+func beforeCommandWhoNeedDatabase(c *cli.Context) (err error) {
+	if err = beforeEveryCommand(c); err != nil {
+		return err
+	}
+
+	// merge configuration from cli and configuration file, only the database is needed
+	config.Merge()
+	if err = config.ApplyDatabaseOptions(); err != nil {
+		return fmt.Errorf("configuration application failed: %v", err)
+	}
+	if migration.M == nil {
+		return fmt.Errorf("%s provider has no schema to migrate", config.Config.Run.Provider.Type)
+	}
+	return nil
+}
+
 func commandRun(_ *cli.Context) error {
+	stopPurge := purge.Start(time.Duration(config.Config.Run.Messages.PurgeInterval) * time.Second)
+	defer stopPurge()
+
+	caCert, caPrivateKey, err := cert.KeyPairFromFiles(
+		config.Config.Run.TLS.ClientsCA.Cert,
+		config.Config.Run.TLS.ClientsCA.Key,
+		[]byte(config.Config.Run.TLS.ClientsCA.KeyPassword),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to load clients certificate authority: %v", err)
+	}
+	stopCRL, err := crl.Start(caCert, caPrivateKey, time.Duration(config.Config.Run.TLS.ClientsCA.CRLInterval)*time.Second)
+	if err != nil {
+		return fmt.Errorf("unable to generate certificate revocation list: %v", err)
+	}
+	defer stopCRL()
+
+	if err = startOCSPResponder(caCert); err != nil {
+		return fmt.Errorf("unable to start ocsp responder: %v", err)
+	}
+
 	log.Infof("Starting Nebulo API server build %s (%s) on %s:%d", BuildVersion, BuildTime, config.Config.Run.Environment.Address, config.Config.Run.Environment.Port)
 	return router.RunTLS(
 		&config.Config.Run.Environment,
@@ -178,6 +295,22 @@ func commandRun(_ *cli.Context) error {
 	)
 }
 
+// startOCSPResponder set the OCSP responder if a delegated responder is configured
+func startOCSPResponder(caCert *x509.Certificate) error {
+	oc := config.Config.Run.TLS.ClientsCA.OCSP
+	if oc.Cert == "" {
+		log.Infoln("ocsp responder disabled")
+		return nil
+	}
+
+	responderCert, responderKey, err := cert.KeyPairFromFiles(oc.Cert, oc.Key, []byte(oc.KeyPassword))
+	if err != nil {
+		return fmt.Errorf("unable to load responder key pair: %v", err)
+	}
+	responder.R, err = responder.New(caCert, responderCert, responderKey, time.Duration(oc.CacheDuration)*time.Second)
+	return err
+}
+
 func commandConfigGen(c *cli.Context) error {
 	conf, err := json.MarshalIndent(config.Config, "", "    ")
 	if err != nil {
@@ -197,3 +330,46 @@ func commandVersion(_ *cli.Context) error {
 	fmt.Printf("nebulo %s (%s)\n", BuildVersion, BuildTime)
 	return nil
 }
+
+func commandMigrateUp(c *cli.Context) error {
+	applied, err := migration.M.Up(c.Int("to"))
+	for _, m := range applied {
+		log.Infof("migration %d applied: %s", m.Version, m.Description)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to migrate up: %v", err)
+	}
+	if len(applied) == 0 {
+		log.Infoln("database schema is up to date")
+	}
+	return nil
+}
+
+func commandMigrateDown(c *cli.Context) error {
+	reverted, err := migration.M.Down(c.Int("to"))
+	for _, m := range reverted {
+		log.Infof("migration %d reverted: %s", m.Version, m.Description)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to migrate down: %v", err)
+	}
+	if len(reverted) == 0 {
+		log.Infoln("no migration to revert")
+	}
+	return nil
+}
+
+func commandMigrateStatus(_ *cli.Context) error {
+	status, err := migration.M.Status()
+	if err != nil {
+		return fmt.Errorf("unable to get migrations status: %v", err)
+	}
+	for _, s := range status {
+		applied := "pending"
+		if s.Applied != nil {
+			applied = s.Applied.Format(time.RFC3339)
+		}
+		fmt.Printf("%03d  %-25s  %s\n", s.Version, applied, s.Description)
+	}
+	return nil
+}
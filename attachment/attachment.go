@@ -0,0 +1,44 @@
+package attachment
+
+import (
+	"errors"
+	"time"
+
+	"github.com/krostar/nebulo-server/channel"
+	"github.com/krostar/nebulo-server/user"
+)
+
+var (
+	// ErrNotFound is throw when an attachment is not found
+	ErrNotFound = errors.New("attachment not found")
+	// ErrNil is throw when an attachment is nil
+	ErrNil = errors.New("attachment is nil")
+	// ErrOffsetMismatch is throw when a chunk does not start where the previous one ended
+	ErrOffsetMismatch = errors.New("chunk offset does not match the received size")
+	// ErrUploadInProgress is throw when a chunk is sent while another chunk of the attachment is being written
+	ErrUploadInProgress = errors.New("another chunk of the attachment is being uploaded")
+	// ErrQuotaExceeded is throw when an attachment would make its owner use more space than allowed
+	ErrQuotaExceeded = errors.New("attachments quota exceeded")
+)
+
+// Attachment is the modelisation of a file encrypted by the client and uploaded
+// in a channel, messages refer to it by its public identifier
+type Attachment struct {
+	ID        int `json:"-" gorm:"column:id; not null"`
+	OwnerID   int `json:"-" gorm:"column:owner_id; not null"`
+	ChannelID int `json:"-" gorm:"column:channel_id; not null"`
+
+	PublicID string          `json:"id" gorm:"column:public_id; size:22; not null"`
+	Owner    user.User       `json:"owner" gorm:"ForeignKey:OwnerID; save_associations:false"`
+	Channel  channel.Channel `json:"-" gorm:"ForeignKey:ChannelID; save_associations:false"`
+	// Size is the total size announced by the owner, Received the size already uploaded
+	Size      int64      `json:"size" gorm:"column:size; not null"`
+	Received  int64      `json:"received" gorm:"column:received; not null" sql:"DEFAULT:0"`
+	Created   time.Time  `json:"created" gorm:"column:created; not null" sql:"DEFAULT:current_timestamp"`
+	Completed *time.Time `json:"completed" gorm:"column:completed" sql:"DEFAULT:NULL"`
+}
+
+// IsComplete return true if the whole attachment has been uploaded
+func (a *Attachment) IsComplete() bool {
+	return a.Completed != nil
+}
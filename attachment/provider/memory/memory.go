@@ -0,0 +1,197 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/krostar/nebulo-server/attachment"
+	"github.com/krostar/nebulo-server/attachment/provider"
+	"github.com/krostar/nebulo-server/channel"
+	"github.com/krostar/nebulo-server/provider/memory"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// Provider implements the methods needed to manage attachments
+// with every rows kept in memory
+type Provider struct {
+	*memory.Store
+	provider.Provider
+}
+
+// Init initialize a memory provider and set it as the used provider
+func Init() error {
+	if memory.S == nil {
+		return memory.ErrStoreIsNil
+	}
+
+	provider.P = &Provider{Store: memory.S}
+	return nil
+}
+
+// Create register a new attachment waiting to be uploaded, the size of every attachments
+// of the owner, complete or not, can't exceed quota
+func (p *Provider) Create(owner user.User, chann channel.Channel, publicID string, size int64, quota int64) (a *attachment.Attachment, err error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.usedSpace(owner)+size > quota {
+		return nil, attachment.ErrQuotaExceeded
+	}
+
+	a = &attachment.Attachment{
+		ID:        p.NextID("attachments"),
+		OwnerID:   owner.ID,
+		ChannelID: chann.ID,
+		PublicID:  publicID,
+		Size:      size,
+		Created:   time.Now().UTC(),
+	}
+	p.Attachments = append(p.Attachments, *a)
+
+	a.Owner = owner
+	a.Channel = chann
+	return a, nil
+}
+
+// FindByPublicID return the attachment of the channel with the given public identifier
+func (p *Provider) FindByPublicID(chann channel.Channel, publicID string) (a *attachment.Attachment, err error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	for _, existing := range p.Attachments {
+		if existing.ChannelID != chann.ID || existing.PublicID != publicID {
+			continue
+		}
+
+		a = &attachment.Attachment{}
+		*a = existing
+		for _, u := range p.Users {
+			if u.ID == a.OwnerID {
+				a.Owner = u
+			}
+		}
+		a.Channel = chann
+		return a, nil
+	}
+	return nil, attachment.ErrNotFound
+}
+
+// ListByChannel return every attachments of the channel
+func (p *Provider) ListByChannel(chann channel.Channel) (list []*attachment.Attachment, err error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	list = []*attachment.Attachment{}
+	for _, existing := range p.Attachments {
+		if existing.ChannelID == chann.ID {
+			a := existing
+			list = append(list, &a)
+		}
+	}
+	return list, nil
+}
+
+// ListByUser return every attachments removed with the user, the ones he owns
+// and the ones of the channels he created
+func (p *Provider) ListByUser(u user.User) (list []*attachment.Attachment, err error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	created := make(map[int]bool)
+	for _, c := range p.Channels {
+		if c.CreatorID == u.ID {
+			created[c.ID] = true
+		}
+	}
+
+	list = []*attachment.Attachment{}
+	for _, existing := range p.Attachments {
+		if existing.OwnerID == u.ID || created[existing.ChannelID] {
+			a := existing
+			list = append(list, &a)
+		}
+	}
+	return list, nil
+}
+
+// UsedSpace return the sum of the size of the owner attachments, complete or not
+func (p *Provider) UsedSpace(owner user.User) (used int64, err error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	return p.usedSpace(owner), nil
+}
+
+// usedSpace return the used space of the owner, it must be called with the lock held
+func (p *Provider) usedSpace(owner user.User) (used int64) {
+	for _, a := range p.Attachments {
+		if a.OwnerID == owner.ID {
+			used += a.Size
+		}
+	}
+	return used
+}
+
+// Received update the uploaded size of the attachment, offset is the uploaded size before
+// the chunk was written and the update fails if an other chunk was written meanwhile
+func (p *Provider) Received(a *attachment.Attachment, offset int64, received int64) (err error) {
+	if a == nil {
+		return attachment.ErrNil
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	var completed *time.Time
+	if received >= a.Size {
+		now := time.Now().UTC()
+		completed = &now
+	}
+
+	for i := range p.Attachments {
+		if p.Attachments[i].ID == a.ID && p.Attachments[i].Received == offset {
+			p.Attachments[i].Received = received
+			p.Attachments[i].Completed = completed
+
+			a.Received = received
+			a.Completed = completed
+			return nil
+		}
+	}
+	return attachment.ErrOffsetMismatch
+}
+
+// Delete remove the attachment
+func (p *Provider) Delete(a *attachment.Attachment) (err error) {
+	if a == nil {
+		return attachment.ErrNil
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	p.remove(func(existing *attachment.Attachment) bool {
+		return existing.ID == a.ID
+	})
+	return nil
+}
+
+// DeleteByChannel remove every attachments of the channel
+func (p *Provider) DeleteByChannel(chann channel.Channel) (err error) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.remove(func(existing *attachment.Attachment) bool {
+		return existing.ChannelID == chann.ID
+	})
+	return nil
+}
+
+func (p *Provider) remove(match func(a *attachment.Attachment) bool) {
+	attachments := p.Attachments[:0]
+	for _, a := range p.Attachments {
+		if !match(&a) {
+			attachments = append(attachments, a)
+		}
+	}
+	p.Attachments = attachments
+}
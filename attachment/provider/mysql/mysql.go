@@ -0,0 +1,26 @@
+package mysql
+
+import (
+	gp "github.com/krostar/nebulo-golib/provider"
+	"github.com/krostar/nebulo-server/attachment/provider"
+	dp "github.com/krostar/nebulo-server/attachment/provider/sql"
+)
+
+// Provider implements the methods needed to manage attachments
+// via a MySQL database
+type Provider struct {
+	dp.Provider
+}
+
+// Init initialize a MySQL provider and set it as the used provider
+func Init() error {
+	if gp.RP == nil {
+		return gp.ErrRPIsNil
+	}
+
+	p := &Provider{}
+	p.RootProvider = gp.RP
+
+	provider.P = p
+	return nil
+}
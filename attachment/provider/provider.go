@@ -0,0 +1,23 @@
+package provider
+
+import (
+	"github.com/krostar/nebulo-server/attachment"
+	"github.com/krostar/nebulo-server/channel"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// Provider contains all the methods needed to manage attachments
+type Provider interface {
+	Create(owner user.User, chann channel.Channel, publicID string, size int64, quota int64) (a *attachment.Attachment, err error)
+	FindByPublicID(chann channel.Channel, publicID string) (a *attachment.Attachment, err error)
+	ListByChannel(chann channel.Channel) (list []*attachment.Attachment, err error)
+	ListByUser(u user.User) (list []*attachment.Attachment, err error)
+	UsedSpace(owner user.User) (used int64, err error)
+
+	Received(a *attachment.Attachment, offset int64, received int64) (err error)
+	Delete(a *attachment.Attachment) (err error)
+	DeleteByChannel(chann channel.Channel) (err error)
+}
+
+// P is the selected provider
+var P Provider
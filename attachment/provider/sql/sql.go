@@ -0,0 +1,164 @@
+package sql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	gp "github.com/krostar/nebulo-golib/provider"
+
+	"github.com/krostar/nebulo-server/attachment"
+	"github.com/krostar/nebulo-server/attachment/provider"
+	"github.com/krostar/nebulo-server/channel"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// Provider implements the methods needed to manage attachments
+// for every SQL based database
+type Provider struct {
+	*gp.RootProvider
+	provider.Provider
+}
+
+// Create register a new attachment waiting to be uploaded, the size of every attachments
+// of the owner, complete or not, can't exceed quota
+func (p *Provider) Create(owner user.User, chann channel.Channel, publicID string, size int64, quota int64) (a *attachment.Attachment, err error) {
+	tx := p.DB.Begin()
+	if err = tx.Error; err != nil {
+		return nil, fmt.Errorf("unable to start transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// the update lock the owner row until the end of the transaction, concurrent creations are checked one by one
+	if err = tx.Model(&user.User{}).Where("id = ?", owner.ID).UpdateColumn("id", gorm.Expr("id")).Error; err != nil {
+		return nil, fmt.Errorf("unable to lock owner %d: %v", owner.ID, err)
+	}
+	used, err := usedSpace(tx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if used+size > quota {
+		return nil, attachment.ErrQuotaExceeded
+	}
+
+	a = &attachment.Attachment{
+		OwnerID:   owner.ID,
+		ChannelID: chann.ID,
+		PublicID:  publicID,
+		Size:      size,
+	}
+	if err = tx.Create(a).Error; err != nil {
+		return nil, fmt.Errorf("unable to insert attachment: %v", err)
+	}
+	if err = tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("unable to commit attachment creation: %v", err)
+	}
+
+	a.Owner = owner
+	a.Channel = chann
+	return a, nil
+}
+
+// FindByPublicID return the attachment of the channel with the given public identifier
+func (p *Provider) FindByPublicID(chann channel.Channel, publicID string) (a *attachment.Attachment, err error) {
+	a = &attachment.Attachment{}
+	query := p.DB.Where(&attachment.Attachment{ChannelID: chann.ID, PublicID: publicID}).First(a)
+	if query.RecordNotFound() {
+		return nil, attachment.ErrNotFound
+	} else if err = query.Error; err != nil {
+		return nil, fmt.Errorf("unable to find attachment: %v", err)
+	}
+
+	if err = p.DB.Find(&a.Owner, a.OwnerID).Error; err != nil {
+		return nil, fmt.Errorf("unable to get owner for attachment %d: %v", a.ID, err)
+	}
+	a.Channel = chann
+	return a, nil
+}
+
+// ListByChannel return every attachments of the channel
+func (p *Provider) ListByChannel(chann channel.Channel) (list []*attachment.Attachment, err error) {
+	list = []*attachment.Attachment{}
+	if err = p.DB.Where(&attachment.Attachment{ChannelID: chann.ID}).Order("id").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("unable to select attachments in db: %v", err)
+	}
+	return list, nil
+}
+
+// ListByUser return every attachments removed with the user, the ones he owns
+// and the ones of the channels he created
+func (p *Provider) ListByUser(u user.User) (list []*attachment.Attachment, err error) {
+	list = []*attachment.Attachment{}
+	if err = p.DB.Where("owner_id = ? OR channel_id IN (SELECT id FROM channels WHERE creator_id = ?)", u.ID, u.ID).
+		Order("id").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("unable to select attachments in db: %v", err)
+	}
+	return list, nil
+}
+
+// UsedSpace return the sum of the size of the owner attachments, complete or not
+func (p *Provider) UsedSpace(owner user.User) (used int64, err error) {
+	return usedSpace(p.DB, owner)
+}
+
+// usedSpace return the used space of the owner seen by the database or the transaction
+func usedSpace(db *gorm.DB, owner user.User) (used int64, err error) {
+	var result struct {
+		Used int64 `gorm:"column:used"`
+	}
+	if err = db.Model(&attachment.Attachment{}).Select("COALESCE(SUM(size), 0) AS used").
+		Where("owner_id = ?", owner.ID).Scan(&result).Error; err != nil {
+		return 0, fmt.Errorf("unable to compute used space: %v", err)
+	}
+	return result.Used, nil
+}
+
+// Received update the uploaded size of the attachment, offset is the uploaded size before
+// the chunk was written and the update fails if an other chunk was written meanwhile
+func (p *Provider) Received(a *attachment.Attachment, offset int64, received int64) (err error) {
+	if a == nil {
+		return attachment.ErrNil
+	}
+
+	fields := map[string]interface{}{"received": received}
+	var completed *time.Time
+	if received >= a.Size {
+		now := time.Now().UTC()
+		completed = &now
+		fields["completed"] = now
+	}
+
+	query := p.DB.Model(&attachment.Attachment{}).Where("id = ? AND received = ?", a.ID, offset).Updates(fields)
+	if err = query.Error; err != nil {
+		return fmt.Errorf("unable to update attachment received size: %v", err)
+	} else if query.RowsAffected == 0 {
+		return attachment.ErrOffsetMismatch
+	}
+
+	a.Received = received
+	a.Completed = completed
+	return nil
+}
+
+// Delete remove the attachment
+func (p *Provider) Delete(a *attachment.Attachment) (err error) {
+	if a == nil {
+		return attachment.ErrNil
+	}
+	if err = p.DB.Delete(a).Error; err != nil {
+		return fmt.Errorf("unable to delete attachment: %v", err)
+	}
+	return nil
+}
+
+// DeleteByChannel remove every attachments of the channel
+func (p *Provider) DeleteByChannel(chann channel.Channel) (err error) {
+	if err = p.DB.Where("channel_id = ?", chann.ID).Delete(&attachment.Attachment{}).Error; err != nil {
+		return fmt.Errorf("unable to delete channel attachments: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package local
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/krostar/nebulo-server/attachment/storage"
+)
+
+// keys are public identifiers, they are checked to not escape the storage directory
+var keyRegexp = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+
+// Storage store attachments contents as files in a local directory
+type Storage struct {
+	Directory string
+}
+
+// Init create the directory if needed and set a local storage as the used storage
+func Init(directory string) error {
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		return fmt.Errorf("unable to create attachments directory: %v", err)
+	}
+
+	storage.S = &Storage{Directory: directory}
+	return nil
+}
+
+func (s *Storage) path(key string) (string, error) {
+	if !keyRegexp.MatchString(key) {
+		return "", storage.ErrInvalidKey
+	}
+	return filepath.Join(s.Directory, key), nil
+}
+
+// WriteAt write the content of r at the given offset of the file
+func (s *Storage) WriteAt(key string, offset int64, r io.Reader) (written int64, err error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("unable to open attachment file: %v", err)
+	}
+	defer func() {
+		if errClose := f.Close(); errClose != nil && err == nil {
+			err = fmt.Errorf("unable to close attachment file: %v", errClose)
+		}
+	}()
+
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("unable to seek attachment file: %v", err)
+	}
+	if written, err = io.Copy(f, r); err != nil {
+		return written, fmt.Errorf("unable to write attachment file: %v", err)
+	}
+	return written, nil
+}
+
+// Open return the content of the file
+func (s *Storage) Open(key string) (c storage.Content, err error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, storage.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to open attachment file: %v", err)
+	}
+	return f, nil
+}
+
+// Delete remove the file, it does not fail if the file does not exist
+func (s *Storage) Delete(key string) (err error) {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to remove attachment file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"errors"
+	"io"
+)
+
+var (
+	// ErrNotFound is throw when the content of an attachment is not found
+	ErrNotFound = errors.New("attachment content not found")
+	// ErrInvalidKey is throw when a key can't be used to store a content
+	ErrInvalidKey = errors.New("invalid attachment key")
+)
+
+// Content is a readable and seekable attachment content
+type Content interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+// Storage contains all the methods needed to store attachments contents
+type Storage interface {
+	// WriteAt write the content of r at the given offset and return the number of written bytes,
+	// even when an error occurred
+	WriteAt(key string, offset int64, r io.Reader) (written int64, err error)
+	Open(key string) (c Content, err error)
+	Delete(key string) (err error)
+}
+
+// S is the selected storage
+var S Storage
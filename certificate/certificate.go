@@ -0,0 +1,99 @@
+package certificate
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/krostar/nebulo-server/user"
+)
+
+var (
+	// ErrNotFound is throw when a certificate is not found
+	ErrNotFound = errors.New("certificate not found")
+	// ErrNil is throw when a certificate is nil
+	ErrNil = errors.New("certificate is nil")
+	// ErrAlreadyRevoked is throw when a certificate is revoked twice
+	ErrAlreadyRevoked = errors.New("certificate is already revoked")
+	// ErrCRLNotFound is throw when no certificate revocation list has been generated yet
+	ErrCRLNotFound = errors.New("certificate revocation list not found")
+)
+
+// Status is the revocation status of an issued certificate
+type Status string
+
+const (
+	// StatusValid is the status of a certificate which has not been revoked, it may be expired
+	StatusValid Status = "valid"
+	// StatusRevoked is the status of a revoked certificate
+	StatusRevoked Status = "revoked"
+)
+
+// serialBits is the number of random bits of the serial numbers
+const serialBits = 128
+
+// Certificate is the modelisation of a client certificate issued to an user
+type Certificate struct {
+	ID     int `json:"-" gorm:"column:id; not null"`
+	UserID int `json:"-" gorm:"column:user_id; not null"`
+
+	// Serial is the hexadecimal representation of the certificate serial number
+	Serial    string     `json:"serial" gorm:"column:serial; size:40; not null"`
+	User      user.User  `json:"-" gorm:"ForeignKey:UserID; save_associations:false"`
+	NotBefore time.Time  `json:"not_before" gorm:"column:not_before; not null"`
+	NotAfter  time.Time  `json:"not_after" gorm:"column:not_after; not null"`
+	Status    Status     `json:"status" gorm:"column:status; size:16; not null"`
+	Issued    time.Time  `json:"issued" gorm:"column:issued; not null" sql:"DEFAULT:current_timestamp"`
+	Revoked   *time.Time `json:"revoked" gorm:"column:revoked" sql:"DEFAULT:NULL"`
+}
+
+// CRL is a certificate revocation list signed by the clients CA
+type CRL struct {
+	ID         int       `json:"-" gorm:"column:id; not null"`
+	ThisUpdate time.Time `json:"this_update" gorm:"column:this_update; not null"`
+	NextUpdate time.Time `json:"next_update" gorm:"column:next_update; not null"`
+	// DER is the signed list, DER encoded
+	DER []byte `json:"-" gorm:"column:der; not null"`
+}
+
+// TableName is the table name in database
+func (c *CRL) TableName() string {
+	return "crls"
+}
+
+// IsRevoked return true if the certificate has been revoked
+func (c *Certificate) IsRevoked() bool {
+	return c.Status == StatusRevoked
+}
+
+// IsExpired return true if the certificate is not valid anymore at the given date
+func (c *Certificate) IsExpired(now time.Time) bool {
+	return now.After(c.NotAfter)
+}
+
+// NewSerial return a random positive serial number
+func NewSerial() (serial *big.Int, err error) {
+	max := new(big.Int).Lsh(big.NewInt(1), serialBits)
+	for serial == nil || serial.Sign() == 0 {
+		if serial, err = rand.Int(rand.Reader, max); err != nil {
+			return nil, fmt.Errorf("unable to generate serial number: %v", err)
+		}
+	}
+	return serial, nil
+}
+
+// FormatSerial return the representation of a serial number used to store it
+func FormatSerial(serial *big.Int) string {
+	return fmt.Sprintf("%x", serial)
+}
+
+// ParseSerial parse the representation of a serial number used to store it
+func ParseSerial(serial string) (*big.Int, error) {
+	s, ok := new(big.Int).SetString(serial, 16)
+	if !ok || s.Sign() <= 0 {
+		return nil, fmt.Errorf("malformed serial number %q", serial)
+	}
+	return s, nil
+}
@@ -0,0 +1,78 @@
+package crl
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"time"
+
+	"github.com/krostar/nebulo-golib/log"
+
+	"github.com/krostar/nebulo-server/certificate"
+	certp "github.com/krostar/nebulo-server/certificate/provider"
+)
+
+// Generate create a certificate revocation list of the revoked certificates which are
+// not expired yet, signed by the CA and valid for the given duration, and store it
+func Generate(caCert *x509.Certificate, caPrivateKey crypto.PrivateKey, now time.Time, validity time.Duration) (crl *certificate.CRL, err error) {
+	now = now.UTC()
+	revoked, err := certp.P.ListRevoked(now)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list revoked certificates: %v", err)
+	}
+
+	revokedCertificates := make([]pkix.RevokedCertificate, 0, len(revoked))
+	for _, c := range revoked {
+		serial, err := certificate.ParseSerial(c.Serial)
+		if err != nil {
+			return nil, err
+		}
+		revokedCertificates = append(revokedCertificates, pkix.RevokedCertificate{
+			SerialNumber:   serial,
+			RevocationTime: c.Revoked.UTC(),
+		})
+	}
+
+	crl = &certificate.CRL{
+		ThisUpdate: now.Truncate(time.Second),
+		NextUpdate: now.Add(validity).Truncate(time.Second),
+	}
+	if crl.DER, err = caCert.CreateCRL(rand.Reader, caPrivateKey, revokedCertificates, crl.ThisUpdate, crl.NextUpdate); err != nil {
+		return nil, fmt.Errorf("unable to create crl: %v", err)
+	}
+	if err = certp.P.SaveCRL(crl); err != nil {
+		return nil, fmt.Errorf("unable to save crl: %v", err)
+	}
+	return crl, nil
+}
+
+// Start generate a certificate revocation list right away and then periodically until stop is called,
+// each list is valid for twice the interval to let clients fetch the next one in time
+func Start(caCert *x509.Certificate, caPrivateKey crypto.PrivateKey, interval time.Duration) (stop func(), err error) {
+	if _, err = Generate(caCert, caPrivateKey, time.Now(), 2*interval); err != nil {
+		return nil, err
+	}
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				if crl, err := Generate(caCert, caPrivateKey, now, 2*interval); err != nil {
+					log.Errorf("unable to generate certificate revocation list: %v", err)
+				} else {
+					log.Debugf("certificate revocation list generated, next update on %s", crl.NextUpdate)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }, nil
+}
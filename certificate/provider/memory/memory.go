@@ -0,0 +1,170 @@
+package memory
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/krostar/nebulo-server/certificate"
+	"github.com/krostar/nebulo-server/certificate/provider"
+	"github.com/krostar/nebulo-server/provider/memory"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// Provider implements the methods needed to manage issued certificates
+// with every rows kept in memory
+type Provider struct {
+	*memory.Store
+	provider.Provider
+}
+
+// Init initialize a memory provider and set it as the used provider
+func Init() error {
+	if memory.S == nil {
+		return memory.ErrStoreIsNil
+	}
+
+	provider.P = &Provider{Store: memory.S}
+	return nil
+}
+
+// Create register a certificate issued to the owner
+func (p *Provider) Create(owner user.User, serial *big.Int, notBefore time.Time, notAfter time.Time) (c *certificate.Certificate, err error) {
+	p.Lock()
+	defer p.Unlock()
+
+	c = &certificate.Certificate{
+		ID:        p.NextID("certificates"),
+		UserID:    owner.ID,
+		Serial:    certificate.FormatSerial(serial),
+		NotBefore: notBefore.UTC(),
+		NotAfter:  notAfter.UTC(),
+		Status:    certificate.StatusValid,
+		Issued:    time.Now().UTC(),
+	}
+	p.Certificates = append(p.Certificates, *c)
+
+	c.User = owner
+	return c, nil
+}
+
+// FindBySerial return the certificate with the given serial number
+func (p *Provider) FindBySerial(serial *big.Int) (c *certificate.Certificate, err error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	formatted := certificate.FormatSerial(serial)
+	for _, existing := range p.Certificates {
+		if existing.Serial != formatted {
+			continue
+		}
+
+		c = &certificate.Certificate{}
+		*c = existing
+		for _, u := range p.Users {
+			if u.ID == c.UserID {
+				c.User = u
+			}
+		}
+		return c, nil
+	}
+	return nil, certificate.ErrNotFound
+}
+
+// FindStatus return the revocation status of the certificate with the given serial number, without loading it
+func (p *Provider) FindStatus(serial *big.Int) (status certificate.Status, err error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	formatted := certificate.FormatSerial(serial)
+	for _, existing := range p.Certificates {
+		if existing.Serial == formatted {
+			return existing.Status, nil
+		}
+	}
+	return "", certificate.ErrNotFound
+}
+
+// ListByUser return every certificates issued to the owner, ordered by issuance
+func (p *Provider) ListByUser(owner user.User) (list []*certificate.Certificate, err error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	list = []*certificate.Certificate{}
+	for _, existing := range p.Certificates {
+		if existing.UserID == owner.ID {
+			c := existing
+			c.User = owner
+			list = append(list, &c)
+		}
+	}
+	return list, nil
+}
+
+// ListRevoked return the revoked certificates which are not expired at the given date, ordered by revocation
+func (p *Provider) ListRevoked(now time.Time) (list []*certificate.Certificate, err error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	list = []*certificate.Certificate{}
+	for _, existing := range p.Certificates {
+		if existing.IsRevoked() && existing.NotAfter.After(now) {
+			c := existing
+			list = append(list, &c)
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Revoked.Before(*list[j].Revoked)
+	})
+	return list, nil
+}
+
+// Revoke mark the certificate as revoked since the given date
+func (p *Provider) Revoke(c *certificate.Certificate, when time.Time) (err error) {
+	if c == nil {
+		return certificate.ErrNil
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	when = when.UTC()
+	for i := range p.Certificates {
+		if p.Certificates[i].ID != c.ID {
+			continue
+		}
+		if p.Certificates[i].IsRevoked() {
+			return certificate.ErrAlreadyRevoked
+		}
+		p.Certificates[i].Status = certificate.StatusRevoked
+		p.Certificates[i].Revoked = &when
+
+		c.Status = certificate.StatusRevoked
+		c.Revoked = &when
+		return nil
+	}
+	return certificate.ErrNotFound
+}
+
+// SaveCRL store a new certificate revocation list in place of the previous ones
+func (p *Provider) SaveCRL(crl *certificate.CRL) (err error) {
+	p.Lock()
+	defer p.Unlock()
+
+	crl.ID = p.NextID("crls")
+	p.CRLs = []certificate.CRL{*crl}
+	return nil
+}
+
+// LastCRL return the last stored certificate revocation list
+func (p *Provider) LastCRL() (crl *certificate.CRL, err error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	if len(p.CRLs) == 0 {
+		return nil, certificate.ErrCRLNotFound
+	}
+	crl = &certificate.CRL{}
+	*crl = p.CRLs[len(p.CRLs)-1]
+	return crl, nil
+}
@@ -0,0 +1,26 @@
+package provider
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/krostar/nebulo-server/certificate"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// Provider contains all the methods needed to manage issued certificates
+type Provider interface {
+	Create(owner user.User, serial *big.Int, notBefore time.Time, notAfter time.Time) (c *certificate.Certificate, err error)
+	FindBySerial(serial *big.Int) (c *certificate.Certificate, err error)
+	FindStatus(serial *big.Int) (status certificate.Status, err error)
+	ListByUser(owner user.User) (list []*certificate.Certificate, err error)
+	ListRevoked(now time.Time) (list []*certificate.Certificate, err error)
+
+	Revoke(c *certificate.Certificate, when time.Time) (err error)
+
+	SaveCRL(crl *certificate.CRL) (err error)
+	LastCRL() (crl *certificate.CRL, err error)
+}
+
+// P is the selected provider
+var P Provider
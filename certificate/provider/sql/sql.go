@@ -0,0 +1,153 @@
+package sql
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	gp "github.com/krostar/nebulo-golib/provider"
+
+	"github.com/krostar/nebulo-server/certificate"
+	"github.com/krostar/nebulo-server/certificate/provider"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// Provider implements the methods needed to manage issued certificates
+// for every SQL based database
+type Provider struct {
+	*gp.RootProvider
+	provider.Provider
+}
+
+// Create register a certificate issued to the owner
+func (p *Provider) Create(owner user.User, serial *big.Int, notBefore time.Time, notAfter time.Time) (c *certificate.Certificate, err error) {
+	c = &certificate.Certificate{
+		UserID:    owner.ID,
+		Serial:    certificate.FormatSerial(serial),
+		NotBefore: notBefore.UTC(),
+		NotAfter:  notAfter.UTC(),
+		Status:    certificate.StatusValid,
+		Issued:    time.Now().UTC(),
+	}
+	if err = p.DB.Create(c).Error; err != nil {
+		return nil, fmt.Errorf("unable to insert certificate: %v", err)
+	}
+
+	c.User = owner
+	return c, nil
+}
+
+// FindBySerial return the certificate with the given serial number
+func (p *Provider) FindBySerial(serial *big.Int) (c *certificate.Certificate, err error) {
+	c = &certificate.Certificate{}
+	query := p.DB.Where(&certificate.Certificate{Serial: certificate.FormatSerial(serial)}).First(c)
+	if query.RecordNotFound() {
+		return nil, certificate.ErrNotFound
+	} else if err = query.Error; err != nil {
+		return nil, fmt.Errorf("unable to find certificate: %v", err)
+	}
+
+	// certificates of deleted users are kept
+	if query = p.DB.Find(&c.User, c.UserID); query.Error != nil && !query.RecordNotFound() {
+		return nil, fmt.Errorf("unable to get user of certificate %s: %v", c.Serial, query.Error)
+	}
+	return c, nil
+}
+
+// FindStatus return the revocation status of the certificate with the given serial number, without loading it
+func (p *Provider) FindStatus(serial *big.Int) (status certificate.Status, err error) {
+	var statuses []string
+	if err = p.DB.Model(&certificate.Certificate{}).Where("serial = ?", certificate.FormatSerial(serial)).
+		Limit(1).Pluck("status", &statuses).Error; err != nil {
+		return "", fmt.Errorf("unable to find certificate status: %v", err)
+	}
+	if len(statuses) == 0 {
+		return "", certificate.ErrNotFound
+	}
+	return certificate.Status(statuses[0]), nil
+}
+
+// ListByUser return every certificates issued to the owner, ordered by issuance
+func (p *Provider) ListByUser(owner user.User) (list []*certificate.Certificate, err error) {
+	list = []*certificate.Certificate{}
+	if err = p.DB.Where(&certificate.Certificate{UserID: owner.ID}).Order("id").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("unable to select certificates in db: %v", err)
+	}
+	for _, c := range list {
+		c.User = owner
+	}
+	return list, nil
+}
+
+// ListRevoked return the revoked certificates which are not expired at the given date, ordered by revocation
+func (p *Provider) ListRevoked(now time.Time) (list []*certificate.Certificate, err error) {
+	list = []*certificate.Certificate{}
+	if err = p.DB.Where("status = ? AND not_after > ?", certificate.StatusRevoked, now.UTC()).
+		Order("revoked, id").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("unable to select revoked certificates in db: %v", err)
+	}
+	return list, nil
+}
+
+// Revoke mark the certificate as revoked since the given date
+func (p *Provider) Revoke(c *certificate.Certificate, when time.Time) (err error) {
+	if c == nil {
+		return certificate.ErrNil
+	}
+
+	when = when.UTC()
+	query := p.DB.Model(&certificate.Certificate{}).Where("id = ? AND status = ?", c.ID, certificate.StatusValid).
+		Updates(map[string]interface{}{"status": certificate.StatusRevoked, "revoked": when})
+	if err = query.Error; err != nil {
+		return fmt.Errorf("unable to revoke certificate: %v", err)
+	} else if query.RowsAffected == 0 {
+		var count int
+		if err = p.DB.Model(&certificate.Certificate{}).Where("id = ?", c.ID).Count(&count).Error; err != nil {
+			return fmt.Errorf("unable to find certificate: %v", err)
+		} else if count == 0 {
+			return certificate.ErrNotFound
+		}
+		return certificate.ErrAlreadyRevoked
+	}
+
+	c.Status = certificate.StatusRevoked
+	c.Revoked = &when
+	return nil
+}
+
+// SaveCRL store a new certificate revocation list in place of the previous ones
+func (p *Provider) SaveCRL(crl *certificate.CRL) (err error) {
+	tx := p.DB.Begin()
+	if err = tx.Error; err != nil {
+		return fmt.Errorf("unable to start transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = tx.Delete(&certificate.CRL{}).Error; err != nil {
+		return fmt.Errorf("unable to delete previous crl: %v", err)
+	}
+	if err = tx.Create(crl).Error; err != nil {
+		return fmt.Errorf("unable to insert crl: %v", err)
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		return fmt.Errorf("unable to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// LastCRL return the last stored certificate revocation list
+func (p *Provider) LastCRL() (crl *certificate.CRL, err error) {
+	crl = &certificate.CRL{}
+	query := p.DB.Order("id DESC").First(crl)
+	if query.RecordNotFound() {
+		return nil, certificate.ErrCRLNotFound
+	} else if err = query.Error; err != nil {
+		return nil, fmt.Errorf("unable to find crl: %v", err)
+	}
+	return crl, nil
+}
@@ -0,0 +1,227 @@
+package responder
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/krostar/nebulo-server/certificate"
+	certp "github.com/krostar/nebulo-server/certificate/provider"
+)
+
+var (
+	// ErrNotDelegated is throw when the responder certificate is not allowed to sign OCSP responses for the CA
+	ErrNotDelegated = errors.New("responder certificate is not an OCSP signing certificate issued by the CA")
+	// ErrMalformedRequest is throw when a request can't be parsed
+	ErrMalformedRequest = errors.New("malformed ocsp request")
+	// ErrUnknownIssuer is throw when a request is about a certificate which is not issued by the CA
+	ErrUnknownIssuer = errors.New("certificate is not issued by the CA")
+)
+
+// Responder answer OCSP requests about the certificates issued by the clients CA,
+// responses are signed by a delegated responder and cached for a while
+type Responder struct {
+	ca            *x509.Certificate
+	caKeyBits     []byte
+	cert          *x509.Certificate
+	key           crypto.Signer
+	cacheDuration time.Duration
+
+	cacheMutex sync.Mutex
+	cache      map[string]map[crypto.Hash]*Response
+	pending    map[cacheKey]*call
+}
+
+// cacheKey identify the responses about a certificate for an issuer hash
+type cacheKey struct {
+	serial string
+	hash   crypto.Hash
+}
+
+// call is a response being created, done is closed once it is created
+type call struct {
+	done      chan struct{}
+	resp      *Response
+	cacheable bool
+	err       error
+}
+
+// Response is a signed OCSP response
+type Response struct {
+	DER        []byte
+	ThisUpdate time.Time
+	NextUpdate time.Time
+}
+
+// R is the responder used to answer OCSP requests, nil if OCSP is disabled
+var R *Responder
+
+// New return a responder for the CA, signing responses with a delegated responder certificate
+// and key; responses are valid and cached for cacheDuration
+func New(ca *x509.Certificate, cert *x509.Certificate, key crypto.PrivateKey, cacheDuration time.Duration) (r *Responder, err error) {
+	if err = cert.CheckSignatureFrom(ca); err != nil {
+		return nil, fmt.Errorf("%v: %v", ErrNotDelegated, err)
+	}
+	delegated := false
+	for _, usage := range cert.ExtKeyUsage {
+		delegated = delegated || usage == x509.ExtKeyUsageOCSPSigning
+	}
+	if !delegated {
+		return nil, ErrNotDelegated
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("responder key is not able to sign")
+	}
+
+	var publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err = asn1.Unmarshal(ca.RawSubjectPublicKeyInfo, &publicKeyInfo); err != nil {
+		return nil, fmt.Errorf("unable to parse CA public key: %v", err)
+	}
+
+	return &Responder{
+		ca:            ca,
+		caKeyBits:     publicKeyInfo.PublicKey.RightAlign(),
+		cert:          cert,
+		key:           signer,
+		cacheDuration: cacheDuration,
+		cache:         make(map[string]map[crypto.Hash]*Response),
+		pending:       make(map[cacheKey]*call),
+	}, nil
+}
+
+// Respond return the signed response to a DER encoded OCSP request
+func (r *Responder) Respond(rawRequest []byte, now time.Time) (resp *Response, err error) {
+	req, err := ocsp.ParseRequest(rawRequest)
+	if err != nil {
+		return nil, ErrMalformedRequest
+	}
+	if err = r.checkIssuer(req); err != nil {
+		return nil, err
+	}
+
+	// responses differ by the hash used to identify the issuer
+	key := cacheKey{serial: certificate.FormatSerial(req.SerialNumber), hash: req.HashAlgorithm}
+	r.cacheMutex.Lock()
+	if cached, ok := r.cache[key.serial][key.hash]; ok && now.Before(cached.NextUpdate) {
+		r.cacheMutex.Unlock()
+		return cached, nil
+	}
+	r.purge(now)
+
+	// concurrent requests about the same certificate wait for the response being created
+	if c, ok := r.pending[key]; ok {
+		r.cacheMutex.Unlock()
+		<-c.done
+		return c.resp, c.err
+	}
+	c := &call{done: make(chan struct{})}
+	r.pending[key] = c
+	r.cacheMutex.Unlock()
+
+	c.resp, c.cacheable, c.err = r.create(req, now)
+
+	r.cacheMutex.Lock()
+	// an eviction during the creation removed the call, its response may be outdated and is not cached
+	if r.pending[key] == c {
+		delete(r.pending, key)
+		if c.err == nil && c.cacheable {
+			if r.cache[key.serial] == nil {
+				r.cache[key.serial] = make(map[crypto.Hash]*Response)
+			}
+			r.cache[key.serial][key.hash] = c.resp
+		}
+	}
+	r.cacheMutex.Unlock()
+	close(c.done)
+	return c.resp, c.err
+}
+
+// create sign a response with the current status of the certificate, the response of
+// an unknown certificate is not cacheable to keep the cache bounded by the issued certificates
+func (r *Responder) create(req *ocsp.Request, now time.Time) (resp *Response, cacheable bool, err error) {
+	template := ocsp.Response{
+		SerialNumber: req.SerialNumber,
+		ThisUpdate:   now.UTC().Truncate(time.Second),
+		NextUpdate:   now.UTC().Add(r.cacheDuration).Truncate(time.Second),
+		Certificate:  r.cert,
+		IssuerHash:   req.HashAlgorithm,
+	}
+	crt, err := certp.P.FindBySerial(req.SerialNumber)
+	switch {
+	case err == certificate.ErrNotFound:
+		template.Status = ocsp.Unknown
+	case err != nil:
+		return nil, false, fmt.Errorf("unable to find certificate: %v", err)
+	case crt.IsRevoked():
+		template.Status = ocsp.Revoked
+		template.RevokedAt = crt.Revoked.UTC()
+		template.RevocationReason = ocsp.Unspecified
+	default:
+		template.Status = ocsp.Good
+	}
+
+	resp = &Response{ThisUpdate: template.ThisUpdate, NextUpdate: template.NextUpdate}
+	if resp.DER, err = ocsp.CreateResponse(r.ca, r.cert, template, r.key); err != nil {
+		return nil, false, fmt.Errorf("unable to create response: %v", err)
+	}
+	return resp, template.Status != ocsp.Unknown, nil
+}
+
+// Evict forget the cached responses about a certificate, it must be called
+// once the certificate status changed, like after a revocation
+func (r *Responder) Evict(serial string) {
+	r.cacheMutex.Lock()
+	defer r.cacheMutex.Unlock()
+	delete(r.cache, serial)
+	for key := range r.pending {
+		if key.serial == serial {
+			delete(r.pending, key)
+		}
+	}
+}
+
+// checkIssuer verify the request is about a certificate issued by the CA
+func (r *Responder) checkIssuer(req *ocsp.Request) (err error) {
+	if !req.HashAlgorithm.Available() {
+		return ErrUnknownIssuer
+	}
+
+	h := req.HashAlgorithm.New()
+	h.Write(r.ca.RawSubject)
+	if !bytes.Equal(h.Sum(nil), req.IssuerNameHash) {
+		return ErrUnknownIssuer
+	}
+
+	h.Reset()
+	h.Write(r.caKeyBits)
+	if !bytes.Equal(h.Sum(nil), req.IssuerKeyHash) {
+		return ErrUnknownIssuer
+	}
+	return nil
+}
+
+// purge remove the expired responses from the cache, it must be called with the lock held
+func (r *Responder) purge(now time.Time) {
+	for serial, responses := range r.cache {
+		for hash, cached := range responses {
+			if !now.Before(cached.NextUpdate) {
+				delete(responses, hash)
+			}
+		}
+		if len(responses) == 0 {
+			delete(r.cache, serial)
+		}
+	}
+}
@@ -12,6 +12,10 @@ var (
 	ErrNotFound = errors.New("channel not found")
 	// ErrNil is throw when a chan is nil
 	ErrNil = errors.New("chan is nil")
+	// ErrAlreadyMember is throw when an user is already invited in or member of a chan
+	ErrAlreadyMember = errors.New("user is already invited in or member of the chan")
+	// ErrMembershipNotFound is throw when an user is neither invited in nor member of a chan
+	ErrMembershipNotFound = errors.New("membership not found")
 )
 
 // Channel is the modelisation of a channel
@@ -19,12 +23,17 @@ type Channel struct {
 	ID        int `json:"-" gorm:"column:id; not null"`
 	CreatorID int `json:"-" gorm:"column:creator_id; not null"`
 
+	PublicID         string      `json:"id" gorm:"column:public_id; size:22; not null"`
 	Name             string      `json:"name" gorm:"column:name; size:64"`
 	Created          time.Time   `json:"created" gorm:"column:created; not null" sql:"DEFAULT:current_timestamp"`
 	Creator          user.User   `json:"creator" gorm:"ForeignKey:CreatorID; save_associations:false"`
 	Members          []user.User `json:"members" gorm:"many2many:channel_memberships"`
 	MembersCanEdit   bool        `json:"members_can_edit" gorm:"column:members_can_edit; not null" sql:"DEFAULT:false"`
 	MembersCanInvite bool        `json:"members_can_invite" gorm:"column:members_can_invite; not null" sql:"DEFAULT:false"`
+	// MessagesTTL is the number of seconds messages are kept before being deleted, 0 to keep them forever
+	MessagesTTL int `json:"messages_ttl" gorm:"column:messages_ttl; not null" sql:"DEFAULT:0"`
+	// MessageSequence is the sequence number of the last message posted in the channel
+	MessageSequence int64 `json:"-" gorm:"column:message_sequence; not null" sql:"DEFAULT:0"`
 }
 
 // UserMembership is the link between a channel and a user
@@ -33,9 +42,15 @@ type UserMembership struct {
 	ChannelID int `json:"-" gorm:"column:channel_id; not null"`
 	UserID    int `json:"-" gorm:"column:user_id; not null"`
 
-	User    user.User `json:"user" gorm:"ForeignKey:UserID; save_associations:false"`
-	Invited time.Time `json:"invited" gorm:"column:invited; not null" sql:"DEFAULT:current_timestamp"`
-	Joined  time.Time `json:"joined" gorm:"column:joined" sql:"DEFAULT:NULL"`
+	User    user.User  `json:"user" gorm:"ForeignKey:UserID; save_associations:false"`
+	Channel Channel    `json:"channel" gorm:"ForeignKey:ChannelID; save_associations:false"`
+	Invited time.Time  `json:"invited" gorm:"column:invited; not null" sql:"DEFAULT:current_timestamp"`
+	Joined  *time.Time `json:"joined" gorm:"column:joined" sql:"DEFAULT:NULL"`
+}
+
+// IsPending return true if the user has been invited but did not join the channel yet
+func (um *UserMembership) IsPending() bool {
+	return um.Joined == nil
 }
 
 // TableName is the table name in database
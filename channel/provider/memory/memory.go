@@ -0,0 +1,316 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/krostar/nebulo-server/channel"
+	"github.com/krostar/nebulo-server/channel/provider"
+	"github.com/krostar/nebulo-server/identifier"
+	"github.com/krostar/nebulo-server/provider/memory"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// Provider implements the methods needed to manage channels
+// with every rows kept in memory
+type Provider struct {
+	*memory.Store
+	provider.Provider
+}
+
+// Init initialize a memory provider and set it as the used provider
+func Init() error {
+	if memory.S == nil {
+		return memory.ErrStoreIsNil
+	}
+
+	provider.P = &Provider{Store: memory.S}
+	return nil
+}
+
+// Create create a channel if needed, or return an exsting one with the same requirements
+func (p *Provider) Create(name string, creator user.User, members []user.User) (c *channel.Channel, err error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if c = p.find(channel.Channel{Name: name, CreatorID: creator.ID}); c != nil {
+		return c, nil
+	}
+
+	c = &channel.Channel{
+		Name:      name,
+		CreatorID: creator.ID,
+		Members:   members,
+		Created:   time.Now().UTC(),
+	}
+	if c.PublicID, err = identifier.New(); err != nil {
+		return nil, fmt.Errorf("unable to generate channel public id: %v", err)
+	}
+	c.ID = p.NextID("channels")
+
+	stored := *c
+	stored.Members = nil
+	p.Channels = append(p.Channels, stored)
+
+	// members of a new channel don't need to accept an invitation
+	now := time.Now().UTC()
+	for _, m := range members {
+		if p.membershipIndex(c.ID, m.ID) >= 0 {
+			continue
+		}
+		p.Memberships = append(p.Memberships, channel.UserMembership{
+			ID:        p.NextID("channel_memberships"),
+			ChannelID: c.ID,
+			UserID:    m.ID,
+			Invited:   now,
+			Joined:    &now,
+		})
+	}
+
+	return c, nil
+}
+
+// Find is used to find a channel from the setted field
+func (p *Provider) Find(toFind channel.Channel) (c *channel.Channel, err error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	if c = p.find(toFind); c == nil {
+		return nil, channel.ErrNotFound
+	}
+	return c, nil
+}
+
+// FindByPublicID is used to find a channel from his public identifier,
+// the channel is found only if the user is one of its members
+func (p *Provider) FindByPublicID(u user.User, publicID string) (c *channel.Channel, err error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	if c = p.find(channel.Channel{PublicID: publicID}); c == nil || !p.isMember(c.ID, u.ID) {
+		return nil, channel.ErrNotFound
+	}
+	p.fillChannel(c)
+	return c, nil
+}
+
+// FindByID is used to find a channel from his ID
+func (p *Provider) FindByID(id int) (c *channel.Channel, err error) {
+	return p.Find(channel.Channel{ID: id})
+}
+
+// List return the channels the user joined, ordered by creation
+func (p *Provider) List(u user.User, offset int, limit int) (list []*channel.Channel, err error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	list = []*channel.Channel{}
+	for _, c := range p.Channels {
+		if !p.isMember(c.ID, u.ID) {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		if limit >= 0 && len(list) >= limit {
+			break
+		}
+
+		chnel := c
+		p.fillChannel(&chnel)
+		list = append(list, &chnel)
+	}
+	return list, nil
+}
+
+// Update only fiew fields from channel
+func (p *Provider) Update(c *channel.Channel, fields map[string]interface{}) (err error) {
+	if c == nil {
+		return channel.ErrNil
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	if err = memory.SetColumns(c, fields); err != nil {
+		return fmt.Errorf("unable to update channel informations: %v", err)
+	}
+	for i := range p.Channels {
+		if p.Channels[i].ID == c.ID {
+			if err = memory.SetColumns(&p.Channels[i], fields); err != nil {
+				return fmt.Errorf("unable to update channel informations: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Delete a channel with all its memberships, messages and attachments
+func (p *Provider) Delete(c *channel.Channel) (err error) {
+	if c == nil {
+		return channel.ErrNil
+	}
+	if c.ID == 0 {
+		return channel.ErrNotFound
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	p.DeleteChannel(c.ID)
+	return nil
+}
+
+// Invite create a pending membership for the invitee
+func (p *Provider) Invite(c *channel.Channel, invitee user.User) (um *channel.UserMembership, err error) {
+	if c == nil {
+		return nil, channel.ErrNil
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	if p.membershipIndex(c.ID, invitee.ID) >= 0 {
+		return nil, channel.ErrAlreadyMember
+	}
+
+	um = &channel.UserMembership{
+		ID:        p.NextID("channel_memberships"),
+		ChannelID: c.ID,
+		UserID:    invitee.ID,
+		Invited:   time.Now().UTC(),
+	}
+	p.Memberships = append(p.Memberships, *um)
+
+	um.User = invitee
+	um.Channel = *c
+	return um, nil
+}
+
+// FindMembership is used to find the membership, pending or not, of an user in a channel from its public identifier
+func (p *Provider) FindMembership(u user.User, publicID string) (um *channel.UserMembership, err error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	c := p.find(channel.Channel{PublicID: publicID})
+	if c == nil {
+		return nil, channel.ErrMembershipNotFound
+	}
+	i := p.membershipIndex(c.ID, u.ID)
+	if i < 0 {
+		return nil, channel.ErrMembershipNotFound
+	}
+
+	um = new(channel.UserMembership)
+	*um = p.Memberships[i]
+	p.fillMembership(um, u)
+	return um, nil
+}
+
+// ListInvitations return the pending memberships of an user
+func (p *Provider) ListInvitations(u user.User) (list []*channel.UserMembership, err error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	list = []*channel.UserMembership{}
+	for _, existing := range p.Memberships {
+		if existing.UserID != u.ID || !existing.IsPending() {
+			continue
+		}
+		um := existing
+		p.fillMembership(&um, u)
+		list = append(list, &um)
+	}
+	return list, nil
+}
+
+// Join accept a pending membership
+func (p *Provider) Join(um *channel.UserMembership) (err error) {
+	if um == nil {
+		return channel.ErrMembershipNotFound
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	now := time.Now().UTC()
+	for i := range p.Memberships {
+		if p.Memberships[i].ID == um.ID {
+			joined := now
+			p.Memberships[i].Joined = &joined
+		}
+	}
+	um.Joined = &now
+	return nil
+}
+
+// Leave delete a membership, pending or not
+func (p *Provider) Leave(um *channel.UserMembership) (err error) {
+	if um == nil || um.ID == 0 {
+		return channel.ErrMembershipNotFound
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	memberships := p.Memberships[:0]
+	for _, existing := range p.Memberships {
+		if existing.ID != um.ID {
+			memberships = append(memberships, existing)
+		}
+	}
+	p.Memberships = memberships
+	return nil
+}
+
+// find return a copy of the first channel matching the non-zero fields of toFind, or nil
+func (p *Provider) find(toFind channel.Channel) *channel.Channel {
+	for _, c := range p.Channels {
+		if (toFind.ID == 0 || c.ID == toFind.ID) &&
+			(toFind.CreatorID == 0 || c.CreatorID == toFind.CreatorID) &&
+			(toFind.PublicID == "" || c.PublicID == toFind.PublicID) &&
+			(toFind.Name == "" || c.Name == toFind.Name) {
+			found := c
+			return &found
+		}
+	}
+	return nil
+}
+
+// fillChannel load the joined members and the creator of a channel
+func (p *Provider) fillChannel(c *channel.Channel) {
+	c.Members = []user.User{}
+	for _, u := range p.Users {
+		if p.isMember(c.ID, u.ID) {
+			c.Members = append(c.Members, u)
+		}
+		if u.ID == c.CreatorID {
+			c.Creator = u
+		}
+	}
+}
+
+func (p *Provider) fillMembership(um *channel.UserMembership, u user.User) {
+	um.User = u
+	if c := p.find(channel.Channel{ID: um.ChannelID}); c != nil {
+		p.fillChannel(c)
+		um.Channel = *c
+	}
+}
+
+// isMember return true if the user joined the channel
+func (p *Provider) isMember(channelID int, userID int) bool {
+	i := p.membershipIndex(channelID, userID)
+	return i >= 0 && !p.Memberships[i].IsPending()
+}
+
+// membershipIndex return the position of the membership in the store, or -1
+func (p *Provider) membershipIndex(channelID int, userID int) int {
+	for i, um := range p.Memberships {
+		if um.ChannelID == channelID && um.UserID == userID {
+			return i
+		}
+	}
+	return -1
+}
@@ -1,21 +1,26 @@
 package provider
 
 import (
-	gp "github.com/krostar/nebulo-golib/provider"
-
 	"github.com/krostar/nebulo-server/channel"
 	"github.com/krostar/nebulo-server/user"
 )
 
 // Provider contains all the methods needed to manage channels
 type Provider interface {
-	gp.TablesManagement
-
 	Create(name string, creator user.User, members []user.User) (c *channel.Channel, err error)
 	Find(toFind channel.Channel) (c *channel.Channel, err error)
-	FindByName(u user.User, name string) (c *channel.Channel, err error)
-	List(u user.User, offset int, limit int) (list map[string]*channel.Channel, err error)
+	FindByPublicID(u user.User, publicID string) (c *channel.Channel, err error)
+	List(u user.User, offset int, limit int) (list []*channel.Channel, err error)
 	FindByID(ID int) (c *channel.Channel, err error)
+
+	Update(c *channel.Channel, fields map[string]interface{}) (err error)
+	Delete(c *channel.Channel) (err error)
+
+	Invite(c *channel.Channel, invitee user.User) (um *channel.UserMembership, err error)
+	FindMembership(u user.User, publicID string) (um *channel.UserMembership, err error)
+	ListInvitations(u user.User) (list []*channel.UserMembership, err error)
+	Join(um *channel.UserMembership) (err error)
+	Leave(um *channel.UserMembership) (err error)
 }
 
 // P is the selected provider
@@ -2,7 +2,6 @@ package sql
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/krostar/nebulo-server/channel"
 	"github.com/krostar/nebulo-server/user"
@@ -22,31 +21,83 @@ func (p *Provider) Find(toFind channel.Channel) (c *channel.Channel, err error)
 	return c, nil
 }
 
-// FindByName is used to find a channel from his name
-func (p *Provider) FindByName(u user.User, name string) (c *channel.Channel, err error) {
+// FindByPublicID is used to find a channel from his public identifier,
+// the channel is found only if the user is one of its members
+func (p *Provider) FindByPublicID(u user.User, publicID string) (c *channel.Channel, err error) {
 	c = new(channel.Channel)
 
-	type fakeChannel struct {
-		channel.Channel
-		Members string
+	query := p.DB.Select("channels.*").
+		Joins("INNER JOIN channel_memberships ON channel_memberships.channel_id = channels.id AND channel_memberships.joined IS NOT NULL").
+		Where("channel_memberships.user_id = ?", u.ID).Where("channels.public_id = ?", publicID).First(c)
+	if query.RecordNotFound() {
+		return nil, channel.ErrNotFound
 	}
-	tmp := fakeChannel{}
-	if err = p.DB.Table("`channels` AS `c`").Select("`c`.*, GROUP_CONCAT(`um`.id) AS `members`").
-		Joins("INNER JOIN `channel_memberships` AS `cc` ON `cc`.`channel_id` = `c`.`id`").
-		Joins("INNER JOIN `channel_memberships` AS `cm` ON `cm`.`channel_id` = `c`.`id`").
-		Joins("INNER JOIN `users` AS `um` ON `cm`.`user_id` = `um`.`id`").
-		Where("`cc`.`user_id` = ?", u.ID).Where("`c`.`name` = ?", name).Group("`c`.`id`").Limit(1).Scan(&tmp).Error; err != nil {
-		return nil, fmt.Errorf("unable to get channels list for user %d: %v", u.ID, err)
+	if err = query.Error; err != nil {
+		return nil, fmt.Errorf("unable to select channel in db: %v", err)
 	}
 
-	c = &tmp.Channel
-	c.Members = []user.User{}
-	membersID := strings.Split(tmp.Members, ",")
-	if err = p.DB.Where(membersID).Find(&c.Members).Error; err != nil {
-		return nil, fmt.Errorf("unable to get channels list for user %d: %v", u.ID, err)
-	}
-	if err = p.DB.Where(c.CreatorID).Find(&c.Creator).Error; err != nil {
-		return nil, fmt.Errorf("unable to get channels list for user %d: %v", u.ID, err)
+	if err = p.fillChannel(c); err != nil {
+		return nil, err
 	}
 	return c, nil
 }
+
+// FindByID is used to find a channel from his ID
+func (p *Provider) FindByID(id int) (c *channel.Channel, err error) {
+	return p.Find(channel.Channel{ID: id})
+}
+
+// fillChannel load the joined members and the creator of a channel
+func (p *Provider) fillChannel(c *channel.Channel) (err error) {
+	return p.fillChannels([]*channel.Channel{c})
+}
+
+// fillChannels load the joined members and the creators of many channels
+// in a constant number of queries, members are ordered by their ID
+func (p *Provider) fillChannels(channels []*channel.Channel) (err error) {
+	if len(channels) == 0 {
+		return nil
+	}
+
+	channelsID := make([]int, 0, len(channels))
+	for _, c := range channels {
+		channelsID = append(channelsID, c.ID)
+	}
+
+	memberships := []channel.UserMembership{}
+	if err = p.DB.Where("channel_id IN (?)", channelsID).Where("joined IS NOT NULL").
+		Order("user_id").Find(&memberships).Error; err != nil {
+		return fmt.Errorf("unable to get members of channels: %v", err)
+	}
+
+	// members and creators are loaded together, most of the time creators are also members
+	usersID := make([]int, 0, len(memberships)+len(channels))
+	for _, c := range channels {
+		usersID = append(usersID, c.CreatorID)
+	}
+	for _, m := range memberships {
+		usersID = append(usersID, m.UserID)
+	}
+
+	users := []user.User{}
+	if err = p.DB.Where("id IN (?)", usersID).Find(&users).Error; err != nil {
+		return fmt.Errorf("unable to get members and creators of channels: %v", err)
+	}
+	usersByID := make(map[int]user.User, len(users))
+	for _, u := range users {
+		usersByID[u.ID] = u
+	}
+
+	channelsByID := make(map[int]*channel.Channel, len(channels))
+	for _, c := range channels {
+		c.Members = []user.User{}
+		c.Creator = usersByID[c.CreatorID]
+		channelsByID[c.ID] = c
+	}
+	for _, m := range memberships {
+		if u, ok := usersByID[m.UserID]; ok {
+			channelsByID[m.ChannelID].Members = append(channelsByID[m.ChannelID].Members, u)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package sql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/krostar/nebulo-server/channel"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// Invite create a pending membership for the invitee
+func (p *Provider) Invite(c *channel.Channel, invitee user.User) (um *channel.UserMembership, err error) {
+	if c == nil {
+		return nil, channel.ErrNil
+	}
+
+	existing := &channel.UserMembership{}
+	query := p.DB.Where("channel_id = ? AND user_id = ?", c.ID, invitee.ID).First(existing)
+	if !query.RecordNotFound() {
+		if err = query.Error; err != nil {
+			return nil, fmt.Errorf("unable to select membership in db: %v", err)
+		}
+		return nil, channel.ErrAlreadyMember
+	}
+
+	um = &channel.UserMembership{
+		ChannelID: c.ID,
+		UserID:    invitee.ID,
+		Invited:   time.Now().UTC(),
+	}
+	if err = p.DB.Create(um).Error; err != nil {
+		return nil, fmt.Errorf("unable to insert membership: %v", err)
+	}
+
+	um.User = invitee
+	um.Channel = *c
+	return um, nil
+}
+
+// FindMembership is used to find the membership, pending or not, of an user in a channel from its public identifier
+func (p *Provider) FindMembership(u user.User, publicID string) (um *channel.UserMembership, err error) {
+	um = new(channel.UserMembership)
+
+	query := p.DB.Select("channel_memberships.*").
+		Joins("INNER JOIN channels ON channels.id = channel_memberships.channel_id").
+		Where("channel_memberships.user_id = ?", u.ID).Where("channels.public_id = ?", publicID).First(um)
+	if query.RecordNotFound() {
+		return nil, channel.ErrMembershipNotFound
+	}
+	if err = query.Error; err != nil {
+		return nil, fmt.Errorf("unable to select membership in db: %v", err)
+	}
+
+	if err = p.fillMembership(um, u); err != nil {
+		return nil, err
+	}
+	return um, nil
+}
+
+// ListInvitations return the pending memberships of an user
+func (p *Provider) ListInvitations(u user.User) (list []*channel.UserMembership, err error) {
+	list = []*channel.UserMembership{}
+	if err = p.DB.Where("user_id = ? AND joined IS NULL", u.ID).Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("unable to get invitations list for user %d: %v", u.ID, err)
+	}
+
+	for _, um := range list {
+		if err = p.fillMembership(um, u); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// Join accept a pending membership
+func (p *Provider) Join(um *channel.UserMembership) (err error) {
+	if um == nil {
+		return channel.ErrMembershipNotFound
+	}
+
+	now := time.Now().UTC()
+	if err = p.DB.Model(um).Update("joined", now).Error; err != nil {
+		return fmt.Errorf("unable to update membership: %v", err)
+	}
+	um.Joined = &now
+	return nil
+}
+
+// Leave delete a membership, pending or not
+func (p *Provider) Leave(um *channel.UserMembership) (err error) {
+	// gorm would delete every memberships without primary key
+	if um == nil || um.ID == 0 {
+		return channel.ErrMembershipNotFound
+	}
+
+	if err = p.DB.Delete(um).Error; err != nil {
+		return fmt.Errorf("unable to delete membership: %v", err)
+	}
+	return nil
+}
+
+func (p *Provider) fillMembership(um *channel.UserMembership, u user.User) (err error) {
+	um.User = u
+
+	c, err := p.FindByID(um.ChannelID)
+	if err != nil {
+		return fmt.Errorf("unable to get channel %d of membership: %v", um.ChannelID, err)
+	}
+	if err = p.fillChannel(c); err != nil {
+		return err
+	}
+
+	um.Channel = *c
+	return nil
+}
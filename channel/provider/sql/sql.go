@@ -2,12 +2,15 @@ package sql
 
 import (
 	"fmt"
-	"strings"
+	"time"
 
 	gp "github.com/krostar/nebulo-golib/provider"
 
+	"github.com/krostar/nebulo-server/attachment"
 	"github.com/krostar/nebulo-server/channel"
 	"github.com/krostar/nebulo-server/channel/provider"
+	"github.com/krostar/nebulo-server/identifier"
+	"github.com/krostar/nebulo-server/message"
 	"github.com/krostar/nebulo-server/user"
 )
 
@@ -32,9 +35,17 @@ func (p *Provider) Create(name string, creator user.User, members []user.User) (
 			CreatorID: creator.ID,
 			Members:   members,
 		}
+		if c.PublicID, err = identifier.New(); err != nil {
+			return nil, fmt.Errorf("unable to generate channel public id: %v", err)
+		}
 		if err = p.DB.Create(c).Error; err != nil {
 			return nil, fmt.Errorf("unable to insert channel: %v", err)
 		}
+		// members of a new channel don't need to accept an invitation
+		if err = p.DB.Model(&channel.UserMembership{}).Where("channel_id = ?", c.ID).
+			Update("joined", time.Now().UTC()).Error; err != nil {
+			return nil, fmt.Errorf("unable to set channel members as joined: %v", err)
+		}
 	}
 	if err != nil {
 		return nil, fmt.Errorf("unable to find channel: %v", err)
@@ -43,38 +54,68 @@ func (p *Provider) Create(name string, creator user.User, members []user.User) (
 	return c, nil
 }
 
-// List return a list of channel
-func (p *Provider) List(u user.User, offset int, limit int) (list map[string]*channel.Channel, err error) {
-	list = make(map[string]*channel.Channel)
-	// TODO: its ugly as hell, done it in a hurry, need to redo, thats bad, some cats dies because of me, see with gorm team?
-	// TODO: please whoever read this, forgive me, I was tired, I'M NOT HERE TO SUFFER OKKAAY?
-
-	type fakeChannel struct {
-		channel.Channel
-		Members string
+// List return the channels the user joined, ordered by creation
+func (p *Provider) List(u user.User, offset int, limit int) (list []*channel.Channel, err error) {
+	list = []*channel.Channel{}
+	if err = p.DB.Select("channels.*").
+		Joins("INNER JOIN channel_memberships ON channel_memberships.channel_id = channels.id AND channel_memberships.joined IS NOT NULL").
+		Where("channel_memberships.user_id = ?", u.ID).Order("channels.id").Limit(limit).Offset(offset).
+		Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("unable to get channels list for user %d: %v", u.ID, err)
 	}
-	tmp := []fakeChannel{}
-	if err = p.DB.Table("`channels` AS `c`").Select("`c`.*, GROUP_CONCAT(`um`.id) AS `members`").
-		Joins("INNER JOIN `channel_memberships` AS `cc` ON `cc`.`channel_id` = `c`.`id`").
-		Joins("INNER JOIN `channel_memberships` AS `cm` ON `cm`.`channel_id` = `c`.`id`").
-		Joins("INNER JOIN `users` AS `um` ON `cm`.`user_id` = `um`.`id`").
-		Where("`cc`.`user_id` = ?", u.ID).Group("`c`.`id`").Limit(limit).Offset(offset).Scan(&tmp).Error; err != nil {
+
+	if err = p.fillChannels(list); err != nil {
 		return nil, fmt.Errorf("unable to get channels list for user %d: %v", u.ID, err)
 	}
+	return list, nil
+}
 
-	for _, c := range tmp {
-		var chnel channel.Channel
-		chnel = c.Channel
-		chnel.Members = []user.User{}
-		membersID := strings.Split(c.Members, ",")
-		if err = p.DB.Where(membersID).Find(&chnel.Members).Error; err != nil {
-			return nil, fmt.Errorf("unable to get channels list for user %d: %v", u.ID, err)
-		}
-		if err = p.DB.Where(chnel.CreatorID).Find(&chnel.Creator).Error; err != nil {
-			return nil, fmt.Errorf("unable to get channels list for user %d: %v", u.ID, err)
-		}
-		list[chnel.Name] = &chnel
+// Update only fiew fields from channel
+func (p *Provider) Update(c *channel.Channel, fields map[string]interface{}) (err error) {
+	if c == nil {
+		return channel.ErrNil
 	}
 
-	return list, nil
+	if err = p.DB.Model(c).Updates(fields).Error; err != nil {
+		return fmt.Errorf("unable to update channel informations: %v", err)
+	}
+	return nil
+}
+
+// Delete a channel with all its memberships, messages and attachments
+func (p *Provider) Delete(c *channel.Channel) (err error) {
+	if c == nil {
+		return channel.ErrNil
+	}
+	// gorm would delete every channels without primary key
+	if c.ID == 0 {
+		return channel.ErrNotFound
+	}
+
+	tx := p.DB.Begin()
+	if err = tx.Error; err != nil {
+		return fmt.Errorf("unable to start transaction: %v", err)
+	}
+
+	if err = tx.Where("channel_id = ?", c.ID).Delete(&message.Message{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("unable to delete channel messages: %v", err)
+	}
+	if err = tx.Where("channel_id = ?", c.ID).Delete(&attachment.Attachment{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("unable to delete channel attachments: %v", err)
+	}
+	if err = tx.Where("channel_id = ?", c.ID).Delete(&channel.UserMembership{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("unable to delete channel memberships: %v", err)
+	}
+	if err = tx.Delete(c).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("unable to delete channel: %v", err)
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		return fmt.Errorf("unable to commit channel deletion: %v", err)
+	}
+	return nil
 }
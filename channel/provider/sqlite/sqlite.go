@@ -24,8 +24,3 @@ func Init() error {
 	provider.P = p
 	return nil
 }
-
-// CreateIndexes create constrains and indexes on channels tables
-func (p *Provider) CreateIndexes() (err error) {
-	return nil
-}
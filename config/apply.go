@@ -7,14 +7,32 @@ import (
 	gp "github.com/krostar/nebulo-golib/provider"
 	gpMySQL "github.com/krostar/nebulo-golib/provider/mysql"
 	gpSQLite "github.com/krostar/nebulo-golib/provider/sqlite"
-	cp "github.com/krostar/nebulo-server/channel/provider"
+	apMemory "github.com/krostar/nebulo-server/attachment/provider/memory"
+	apMySQL "github.com/krostar/nebulo-server/attachment/provider/mysql"
+	apPostgres "github.com/krostar/nebulo-server/attachment/provider/postgres"
+	apSQLite "github.com/krostar/nebulo-server/attachment/provider/sqlite"
+	asLocal "github.com/krostar/nebulo-server/attachment/storage/local"
+	certpMemory "github.com/krostar/nebulo-server/certificate/provider/memory"
+	certpMySQL "github.com/krostar/nebulo-server/certificate/provider/mysql"
+	certpPostgres "github.com/krostar/nebulo-server/certificate/provider/postgres"
+	certpSQLite "github.com/krostar/nebulo-server/certificate/provider/sqlite"
+	cpMemory "github.com/krostar/nebulo-server/channel/provider/memory"
 	cpMySQL "github.com/krostar/nebulo-server/channel/provider/mysql"
+	cpPostgres "github.com/krostar/nebulo-server/channel/provider/postgres"
 	cpSQLite "github.com/krostar/nebulo-server/channel/provider/sqlite"
-	mp "github.com/krostar/nebulo-server/message/provider"
+	mpMemory "github.com/krostar/nebulo-server/message/provider/memory"
 	mpMySQL "github.com/krostar/nebulo-server/message/provider/mysql"
+	mpPostgres "github.com/krostar/nebulo-server/message/provider/postgres"
 	mpSQLite "github.com/krostar/nebulo-server/message/provider/sqlite"
-	up "github.com/krostar/nebulo-server/user/provider"
+	"github.com/krostar/nebulo-server/migration"
+	mgMySQL "github.com/krostar/nebulo-server/migration/mysql"
+	mgPostgres "github.com/krostar/nebulo-server/migration/postgres"
+	mgSQLite "github.com/krostar/nebulo-server/migration/sqlite"
+	gpMemory "github.com/krostar/nebulo-server/provider/memory"
+	gpPostgres "github.com/krostar/nebulo-server/provider/postgres"
+	upMemory "github.com/krostar/nebulo-server/user/provider/memory"
 	upMySQL "github.com/krostar/nebulo-server/user/provider/mysql"
+	upPostgres "github.com/krostar/nebulo-server/user/provider/postgres"
 	upSQLite "github.com/krostar/nebulo-server/user/provider/sqlite"
 
 	"github.com/krostar/nebulo-golib/log"
@@ -22,6 +40,20 @@ import (
 	validator "gopkg.in/validator.v2"
 )
 
+const (
+	// defaultPurgeInterval is the default number of seconds between two purges of the expired messages
+	defaultPurgeInterval = 60
+	// defaultCRLInterval is the default number of seconds between two generations of the certificate revocation list
+	defaultCRLInterval = 3600
+	// defaultOCSPCacheDuration is the default number of seconds OCSP responses are valid and cached
+	defaultOCSPCacheDuration = 3600
+
+	defaultAttachmentsStorage        = "local"
+	defaultAttachmentsLocalDirectory = "attachments"
+	defaultAttachmentsQuota          = 100 << 20
+	defaultAttachmentsMaxSize        = 25 << 20
+)
+
 // Apply validate configuration and initialize needed package with
 // values from configuration
 func Apply() (err error) {
@@ -35,12 +67,38 @@ func Apply() (err error) {
 	}
 
 	applyEnvironmentOptions(&Config.Run.Environment)
+	applyTLSOptions(&Config.Run.TLS)
+	applyMessagesOptions(&Config.Run.Messages)
 
 	err = ApplyProvidersOptions(&Config.Run.Provider)
 	if err != nil {
 		return fmt.Errorf("apply providers configuration failed: %v", err)
 	}
+	if err = checkSchema(); err != nil {
+		return fmt.Errorf("database schema check failed: %v", err)
+	}
+
+	if err = applyAttachmentsOptions(&Config.Run.Attachments); err != nil {
+		return fmt.Errorf("apply attachments configuration failed: %v", err)
+	}
+
+	return nil
+}
+
+// ApplyDatabaseOptions validate the providers configuration and initialize the providers
+// without checking the database schema, it is used to migrate the database
+func ApplyDatabaseOptions() (err error) {
+	if err = validator.Validate(Config.Run.Provider); err != nil {
+		return err
+	}
 
+	if err = ApplyLoggingOptions(&Config.Global.Logging); err != nil {
+		return fmt.Errorf("apply logging configuration failed: %v", err)
+	}
+
+	if err = ApplyProvidersOptions(&Config.Run.Provider); err != nil {
+		return fmt.Errorf("apply providers configuration failed: %v", err)
+	}
 	return nil
 }
 
@@ -71,20 +129,60 @@ func applyEnvironmentOptions(ec *env.Config) {
 	}
 }
 
-// ApplyProvidersOptions apply configuration on providers package
-func ApplyProvidersOptions(pc *providerOptions) (err error) {
-	pdc := gp.DefaultConfig{
-		CreateTablesIfNotExists: pc.CreateTablesIfNotExists,
-		DropTablesIfExists:      pc.DropTablesIfExists,
+func applyTLSOptions(tc *tlsOptions) {
+	if tc.ClientsCA.CRLInterval == 0 {
+		tc.ClientsCA.CRLInterval = defaultCRLInterval
+	}
+	if tc.ClientsCA.OCSP.CacheDuration == 0 {
+		tc.ClientsCA.OCSP.CacheDuration = defaultOCSPCacheDuration
+	}
+}
+
+func applyMessagesOptions(mc *messagesOptions) {
+	if mc.PurgeInterval == 0 {
+		mc.PurgeInterval = defaultPurgeInterval
+	}
+}
+
+func applyAttachmentsOptions(ac *attachmentsOptions) (err error) {
+	if ac.Storage == "" {
+		ac.Storage = defaultAttachmentsStorage
+	}
+	if ac.Quota == 0 {
+		ac.Quota = defaultAttachmentsQuota
+	}
+	if ac.MaxSize == 0 {
+		ac.MaxSize = defaultAttachmentsMaxSize
 	}
 
+	switch ac.Storage {
+	case "local":
+		if ac.Local.Directory == "" {
+			ac.Local.Directory = defaultAttachmentsLocalDirectory
+		}
+		err = asLocal.Init(ac.Local.Directory)
+	default:
+		err = errors.New("unknown storage")
+	}
+	if err != nil {
+		return fmt.Errorf("%s attachments storage initialization failed: %v", ac.Storage, err)
+	}
+
+	log.Infof("attachments stored via %s storage", ac.Storage)
+	return nil
+}
+
+// ApplyProvidersOptions apply configuration on providers package
+func ApplyProvidersOptions(pc *providerOptions) (err error) {
 	switch pc.Type {
 	case "sqlite":
-		pc.SQLiteConfig.DefaultConfig = pdc
 		err = gpSQLite.Use(&pc.SQLiteConfig)
 	case "mysql":
-		pc.MySQLConfig.DefaultConfig = pdc
 		err = gpMySQL.Use(&pc.MySQLConfig)
+	case "postgres":
+		err = gpPostgres.Use(&pc.PostgresConfig)
+	case "memory":
+		err = gpMemory.Use()
 	default:
 		err = errors.New("unknown provider")
 	}
@@ -97,10 +195,7 @@ func ApplyProvidersOptions(pc *providerOptions) (err error) {
 		return fmt.Errorf("unable to initialized providers: %v", err)
 	}
 
-	err = resetProviders(&pdc)
-	if err != nil {
-		return fmt.Errorf("unable to reset providers: %v", err)
-	}
+	initMigrator(pc)
 	return nil
 }
 
@@ -116,6 +211,12 @@ func initProviders(pc *providerOptions) (err error) {
 		if err = mpSQLite.Init(); err != nil {
 			return fmt.Errorf("sqlite message providers initialization failed: %v", err)
 		}
+		if err = apSQLite.Init(); err != nil {
+			return fmt.Errorf("sqlite attachment providers initialization failed: %v", err)
+		}
+		if err = certpSQLite.Init(); err != nil {
+			return fmt.Errorf("sqlite certificate providers initialization failed: %v", err)
+		}
 	case "mysql":
 		if err = upMySQL.Init(); err != nil {
 			return fmt.Errorf("mysql user providers initialization failed: %v", err)
@@ -126,41 +227,73 @@ func initProviders(pc *providerOptions) (err error) {
 		if err = mpMySQL.Init(); err != nil {
 			return fmt.Errorf("mysql message providers initialization failed: %v", err)
 		}
+		if err = apMySQL.Init(); err != nil {
+			return fmt.Errorf("mysql attachment providers initialization failed: %v", err)
+		}
+		if err = certpMySQL.Init(); err != nil {
+			return fmt.Errorf("mysql certificate providers initialization failed: %v", err)
+		}
+	case "postgres":
+		if err = upPostgres.Init(); err != nil {
+			return fmt.Errorf("postgres user providers initialization failed: %v", err)
+		}
+		if err = cpPostgres.Init(); err != nil {
+			return fmt.Errorf("postgres channel providers initialization failed: %v", err)
+		}
+		if err = mpPostgres.Init(); err != nil {
+			return fmt.Errorf("postgres message providers initialization failed: %v", err)
+		}
+		if err = apPostgres.Init(); err != nil {
+			return fmt.Errorf("postgres attachment providers initialization failed: %v", err)
+		}
+		if err = certpPostgres.Init(); err != nil {
+			return fmt.Errorf("postgres certificate providers initialization failed: %v", err)
+		}
+	case "memory":
+		if err = upMemory.Init(); err != nil {
+			return fmt.Errorf("memory user providers initialization failed: %v", err)
+		}
+		if err = cpMemory.Init(); err != nil {
+			return fmt.Errorf("memory channel providers initialization failed: %v", err)
+		}
+		if err = mpMemory.Init(); err != nil {
+			return fmt.Errorf("memory message providers initialization failed: %v", err)
+		}
+		if err = apMemory.Init(); err != nil {
+			return fmt.Errorf("memory attachment providers initialization failed: %v", err)
+		}
+		if err = certpMemory.Init(); err != nil {
+			return fmt.Errorf("memory certificate providers initialization failed: %v", err)
+		}
 	default:
 		return fmt.Errorf("providers initialization failed: unknown %v provider", pc.Type)
 	}
 
-	log.Infof("users, channels and messages provided via %s", pc.Type)
+	log.Infof("users, channels, messages, attachments and certificates provided via %s", pc.Type)
 	return nil
 }
 
-func resetProviders(pdc *gp.DefaultConfig) (err error) {
-	if pdc.DropTablesIfExists {
-		err = cp.P.DropTables()
-		if err == nil {
-			err = up.P.DropTables()
-		}
-		if err == nil {
-			err = mp.P.DropTables()
-		}
+// initMigrator select the migrations of the provider, the memory provider has no schema to migrate
+func initMigrator(pc *providerOptions) {
+	switch pc.Type {
+	case "sqlite":
+		migration.M = migration.New(gp.RP.DB, mgSQLite.Migrations)
+	case "mysql":
+		migration.M = migration.New(gp.RP.DB, mgMySQL.Migrations)
+	case "postgres":
+		migration.M = migration.New(gp.RP.DB, mgPostgres.Migrations)
+	default:
+		migration.M = nil
 	}
-	if err == nil && pdc.CreateTablesIfNotExists {
-		err = cp.P.CreateTables()
-		if err == nil {
-			err = up.P.CreateTables()
-		}
-		if err == nil {
-			err = mp.P.CreateTables()
-		}
-		if err == nil {
-			err = cp.P.CreateIndexes()
-		}
-		if err == nil {
-			err = up.P.CreateIndexes()
-		}
-		if err == nil {
-			err = mp.P.CreateIndexes()
-		}
+}
+
+// checkSchema refuse a database whose schema is not the one expected by the providers
+func checkSchema() (err error) {
+	if migration.M == nil {
+		return nil
 	}
-	return err
+	if err = migration.M.Check(); err != nil {
+		return fmt.Errorf("%v, see the migrate command", err)
+	}
+	return nil
 }
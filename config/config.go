@@ -9,6 +9,7 @@ import (
 	gp "github.com/krostar/nebulo-golib/provider"
 	"github.com/krostar/nebulo-golib/tools"
 	"github.com/krostar/nebulo-server/env"
+	"github.com/krostar/nebulo-server/provider/postgres"
 	_ "github.com/krostar/nebulo-server/validator" // used to init custom validators before using them
 )
 
@@ -22,9 +23,32 @@ type logOptions struct {
 }
 
 type runOptions struct {
-	Environment env.Config      `json:"env"`
-	TLS         tlsOptions      `json:"tls"`
-	Provider    providerOptions `json:"provider"`
+	Environment env.Config         `json:"env"`
+	TLS         tlsOptions         `json:"tls"`
+	Provider    providerOptions    `json:"provider"`
+	Messages    messagesOptions    `json:"messages"`
+	Attachments attachmentsOptions `json:"attachments"`
+}
+
+type attachmentsOptions struct {
+	Storage string                  `json:"storage" validate:"regexp=^(local)?$"`
+	Local   localAttachmentsOptions `json:"local"`
+	// Quota is the maximum number of bytes of attachments an user can own
+	Quota int64 `json:"quota"`
+	// MaxSize is the maximum number of bytes of a single attachment
+	MaxSize int64 `json:"max_size"`
+}
+
+type localAttachmentsOptions struct {
+	Directory string `json:"directory"`
+}
+
+type messagesOptions struct {
+	// PurgeInterval is the number of seconds between two purges of the expired messages,
+	// 0 use the default interval and a negative value disable the purge
+	PurgeInterval int `json:"purge_interval"`
+	// DeleteAfterDelivery let receivers acknowledge messages to delete their copies from the server
+	DeleteAfterDelivery bool `json:"delete_after_delivery"`
 }
 
 type tlsOptions struct {
@@ -37,16 +61,28 @@ type tlsClientsCA struct {
 	Cert        string `json:"cert" validate:"file=readable"`
 	Key         string `json:"key" validate:"file=readable"`
 	KeyPassword string `json:"key_password"`
+	// CRLInterval is the number of seconds between two generations of the certificate revocation list,
+	// 0 use the default interval
+	CRLInterval int `json:"crl_interval" validate:"min=0"`
+	// OCSP is the delegated responder used to answer OCSP requests, the responder is disabled without certificate
+	OCSP tlsOCSPResponder `json:"ocsp"`
 }
 
-type providerOptions struct {
-	Type string `json:"type" validate:"regexp=^(sqlite|mysql)?$"`
+type tlsOCSPResponder struct {
+	Cert        string `json:"cert" validate:"file=omitempty+readable"`
+	Key         string `json:"key" validate:"file=omitempty+readable"`
+	KeyPassword string `json:"key_password"`
+	// CacheDuration is the number of seconds OCSP responses are valid and cached,
+	// 0 use the default duration
+	CacheDuration int `json:"cache_duration" validate:"min=0"`
+}
 
-	CreateTablesIfNotExists bool `json:"-"`
-	DropTablesIfExists      bool `json:"-"`
+type providerOptions struct {
+	Type string `json:"type" validate:"regexp=^(sqlite|mysql|postgres|memory)?$"`
 
-	SQLiteConfig gp.SQLiteConfig `json:"sqlite"`
-	MySQLConfig  gp.MySQLConfig  `json:"mysql"`
+	SQLiteConfig   gp.SQLiteConfig `json:"sqlite"`
+	MySQLConfig    gp.MySQLConfig  `json:"mysql"`
+	PostgresConfig postgres.Config `json:"postgres"`
 }
 
 // Options list all the available configurations
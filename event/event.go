@@ -0,0 +1,60 @@
+package event
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Type is the kind of an event
+type Type string
+
+const (
+	// MessageCreated is sent to every receiver of a freshly posted message
+	MessageCreated Type = "message_created"
+	// MessageEdited is sent to every receivers of an edited message
+	MessageEdited Type = "message_edited"
+	// MessageDeleted is sent to every receivers of a deleted message
+	MessageDeleted Type = "message_deleted"
+	// MessageSeen is sent to the sender of a message when one of its receivers read it
+	MessageSeen Type = "message_seen"
+	// MessageAcknowledged is sent to the sender of a message when one of its receivers
+	// acknowledged it and his copy has been deleted
+	MessageAcknowledged Type = "message_acknowledged"
+	// ChannelCreated is sent to the creator of a new channel
+	ChannelCreated Type = "channel_created"
+	// ChannelUpdated is sent to every members of an edited channel
+	ChannelUpdated Type = "channel_updated"
+	// ChannelDeleted is sent to every members of a deleted channel
+	ChannelDeleted Type = "channel_deleted"
+	// MembershipCreated is sent to an user when he became member of a channel
+	MembershipCreated Type = "membership_created"
+	// InvitationCreated is sent to an user invited in a channel
+	InvitationCreated Type = "invitation_created"
+	// MembershipJoined is sent to every members of a channel an user joined
+	MembershipJoined Type = "membership_joined"
+	// MembershipLeft is sent to every members of a channel an user left or declined to join
+	MembershipLeft Type = "membership_left"
+)
+
+// lastID start from the current time to keep IDs increasing across restarts,
+// microseconds are used to keep IDs representable in javascript numbers
+var lastID = uint64(time.Now().UnixNano() / int64(time.Microsecond))
+
+// Event is the modelisation of something that happened
+// and that need to be pushed to online users
+type Event struct {
+	ID      uint64      `json:"id"`
+	Type    Type        `json:"type"`
+	Channel string      `json:"channel"`
+	Data    interface{} `json:"data"`
+}
+
+// New create an event with an unique and increasing ID
+func New(t Type, channelName string, data interface{}) *Event {
+	return &Event{
+		ID:      atomic.AddUint64(&lastID, 1),
+		Type:    t,
+		Channel: channelName,
+		Data:    data,
+	}
+}
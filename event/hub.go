@@ -0,0 +1,169 @@
+package event
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// ErrSlowConsumer is throw when a subscription was dropped
+	// because it was not able to receive events fast enough
+	ErrSlowConsumer = errors.New("subscription dropped: events were not consumed fast enough")
+)
+
+// Subscription receive the events published for an user
+type Subscription struct {
+	UserID int
+	// C is closed when the subscription ends
+	C <-chan *Event
+
+	c      chan *Event
+	err    error
+	closed bool
+}
+
+// Err return the reason why the subscription has been closed, if any
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+// history keep the last events of an user to allow clients to catch up after a reconnection
+type history struct {
+	events []*Event
+	// evicted is the ID of the last event that no longer fit in the history
+	evicted uint64
+}
+
+// Hub dispatch events to the subscriptions of online users
+type Hub struct {
+	mutex         sync.Mutex
+	subscriptions map[int]map[*Subscription]struct{}
+	histories     map[int]*history
+	historySize   int
+	// created is the last event ID before the hub creation,
+	// events older than that can't be remembered
+	created uint64
+}
+
+// H is the hub used to dispatch events
+var H = NewHub(256)
+
+// NewHub return a hub without any subscription which remember
+// at most historySize events per user
+func NewHub(historySize int) *Hub {
+	return &Hub{
+		subscriptions: make(map[int]map[*Subscription]struct{}),
+		histories:     make(map[int]*history),
+		historySize:   historySize,
+		created:       atomic.LoadUint64(&lastID),
+	}
+}
+
+// Subscribe register a new subscription for the user,
+// buffer is the number of events the subscription can hold before being dropped
+func (h *Hub) Subscribe(userID int, buffer int) *Subscription {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.subscribe(userID, buffer)
+}
+
+// SubscribeSince works like Subscribe but also return the events published for
+// the user after the since event ID, complete is false if some of them are no longer remembered
+func (h *Hub) SubscribeSince(userID int, buffer int, since uint64) (s *Subscription, missed []*Event, complete bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	// an ID never given, like one given before a restart with the clock set back,
+	// can't tell which events are missed: every remembered events are
+	if since > atomic.LoadUint64(&lastID) {
+		since = 0
+	}
+
+	complete = since >= h.created
+	if hist, ok := h.histories[userID]; ok {
+		complete = complete && since >= hist.evicted
+		for _, e := range hist.events {
+			if e.ID > since {
+				missed = append(missed, e)
+			}
+		}
+	}
+
+	return h.subscribe(userID, buffer), missed, complete
+}
+
+func (h *Hub) subscribe(userID int, buffer int) *Subscription {
+	c := make(chan *Event, buffer)
+	s := &Subscription{
+		UserID: userID,
+		C:      c,
+		c:      c,
+	}
+
+	if h.subscriptions[userID] == nil {
+		h.subscriptions[userID] = make(map[*Subscription]struct{})
+	}
+	h.subscriptions[userID][s] = struct{}{}
+	return s
+}
+
+// Unsubscribe remove the subscription from the hub and close it
+func (h *Hub) Unsubscribe(s *Subscription) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.unsubscribe(s, nil)
+}
+
+func (h *Hub) unsubscribe(s *Subscription, reason error) {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.err = reason
+	close(s.c)
+
+	delete(h.subscriptions[s.UserID], s)
+	if len(h.subscriptions[s.UserID]) == 0 {
+		delete(h.subscriptions, s.UserID)
+	}
+}
+
+// Publish send the event to every subscriptions of the users,
+// subscriptions that can't keep up are dropped instead of blocking the publisher
+func (h *Hub) Publish(e *Event, usersID ...int) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, userID := range usersID {
+		h.remember(userID, e)
+
+		for s := range h.subscriptions[userID] {
+			select {
+			case s.c <- e:
+			default:
+				h.unsubscribe(s, ErrSlowConsumer)
+			}
+		}
+	}
+}
+
+func (h *Hub) remember(userID int, e *Event) {
+	if h.historySize <= 0 {
+		return
+	}
+
+	hist, ok := h.histories[userID]
+	if !ok {
+		hist = &history{}
+		h.histories[userID] = hist
+	}
+
+	if len(hist.events) >= h.historySize {
+		hist.evicted = hist.events[0].ID
+		hist.events = append(hist.events[:0], hist.events[1:]...)
+	}
+	hist.events = append(hist.events, e)
+}
@@ -0,0 +1,177 @@
+package event
+
+import "testing"
+
+// publish send count events to the user and return them
+func publish(h *Hub, userID int, count int) (events []*Event) {
+	for i := 0; i < count; i++ {
+		e := New(MessageCreated, "chan", i)
+		h.Publish(e, userID)
+		events = append(events, e)
+	}
+	return events
+}
+
+// sameEvents return true if both lists contain the same events in the same order
+func sameEvents(a []*Event, b []*Event) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestHubHistory(t *testing.T) {
+	if H.historySize != 256 {
+		t.Errorf("hub should remember 256 events per user, got %d", H.historySize)
+	}
+
+	tests := []struct {
+		name        string
+		historySize int
+		published   int
+		// kept is the number of last published events still remembered
+		kept int
+	}{
+		{"empty", 256, 0, 0},
+		{"partially filled", 256, 10, 10},
+		{"filled", 256, 256, 256},
+		{"oldest evicted", 256, 257, 256},
+		{"many evicted", 4, 10, 4},
+		{"disabled", 0, 3, 0},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHub(tt.historySize)
+			events := publish(h, 1, tt.published)
+			publish(h, 2, 1)
+
+			since := h.created
+			if len(events) > 0 {
+				since = events[0].ID - 1
+			}
+			s, missed, _ := h.SubscribeSince(1, 1, since)
+			defer h.Unsubscribe(s)
+
+			if kept := events[len(events)-tt.kept:]; !sameEvents(missed, kept) {
+				t.Errorf("history should contain the last %d events in order, got %d events", tt.kept, len(missed))
+			}
+			if hist := h.histories[1]; hist != nil && len(hist.events) > tt.historySize {
+				t.Errorf("history should not grow past %d events, got %d", tt.historySize, len(hist.events))
+			}
+		})
+	}
+}
+
+func TestHubSubscribeSince(t *testing.T) {
+	h := NewHub(4)
+	before := New(MessageCreated, "chan", nil) // older than every remembered events
+	events := publish(h, 1, 6)
+	publish(h, 2, 2)
+
+	tests := []struct {
+		name     string
+		since    uint64
+		missed   []*Event
+		complete bool
+	}{
+		{"up to date", events[5].ID, nil, true},
+		{"missed the last ones", events[3].ID, events[4:], true},
+		{"missed every remembered ones", events[1].ID, events[2:], true},
+		{"missed an evicted one", events[0].ID, events[2:], false},
+		{"missed every evicted ones", before.ID, events[2:], false},
+		{"before the hub creation", h.created - 1, events[2:], false},
+		{"without event", 0, events[2:], false},
+		{"never given", lastID + 1000, events[2:], false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			s, missed, complete := h.SubscribeSince(1, 1, tt.since)
+			defer h.Unsubscribe(s)
+
+			if !sameEvents(missed, tt.missed) {
+				t.Errorf("subscription should return %d missed events, got %d", len(tt.missed), len(missed))
+			}
+			if complete != tt.complete {
+				t.Errorf("missed events completeness should be %t, got %t", tt.complete, complete)
+			}
+		})
+	}
+
+	fresh := NewHub(4)
+	s, missed, complete := fresh.SubscribeSince(1, 1, fresh.created)
+	if len(missed) != 0 || !complete {
+		t.Errorf("a hub without history should return no missed events, got %d (complete %t)", len(missed), complete)
+	}
+	if s.UserID != 1 {
+		t.Errorf("subscription should be for user 1, got %d", s.UserID)
+	}
+}
+
+func TestHubSlowSubscriber(t *testing.T) {
+	tests := []struct {
+		name      string
+		buffer    int
+		published int
+		dropped   bool
+	}{
+		{"without events", 2, 0, false},
+		{"filled buffer", 2, 2, false},
+		{"full buffer", 2, 3, true},
+		{"unbuffered", 0, 1, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHub(0)
+			s := h.Subscribe(1, tt.buffer)
+			fast := h.Subscribe(1, tt.published)
+			other := h.Subscribe(2, 0)
+			events := publish(h, 1, tt.published)
+
+			if dropped := s.closed; dropped != tt.dropped {
+				t.Fatalf("subscription dropped should be %t, got %t", tt.dropped, dropped)
+			}
+			if tt.dropped {
+				if s.Err() != ErrSlowConsumer {
+					t.Errorf("dropped subscription error should be %v, got %v", ErrSlowConsumer, s.Err())
+				}
+				// the events received before being dropped can still be consumed
+				var received []*Event
+				for e := range s.C {
+					received = append(received, e)
+				}
+				if !sameEvents(received, events[:tt.buffer]) {
+					t.Errorf("dropped subscription should hold %d events, got %d", tt.buffer, len(received))
+				}
+				if _, ok := h.subscriptions[1][s]; ok {
+					t.Errorf("dropped subscription should be removed from the hub")
+				}
+			}
+
+			if fast.closed || len(fast.C) != tt.published {
+				t.Errorf("subscription able to keep up should receive %d events, got %d", tt.published, len(fast.C))
+			}
+			if other.closed {
+				t.Errorf("subscription of an other user should not be dropped")
+			}
+
+			h.Unsubscribe(s)
+			if tt.dropped && s.Err() != ErrSlowConsumer {
+				t.Errorf("unsubscribing a dropped subscription should keep its error, got %v", s.Err())
+			}
+			if !tt.dropped && s.Err() != nil {
+				t.Errorf("unsubscribed subscription should have no error, got %v", s.Err())
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package identifier
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+)
+
+// Length is the length of a generated identifier
+const Length = 22
+
+// New generate a random and unguessable identifier, safe to use in urls
+func New() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("unable to read random bytes: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
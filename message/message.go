@@ -1,12 +1,65 @@
 package message
 
 import (
+	"errors"
 	"time"
 
 	"github.com/krostar/nebulo-server/channel"
 	"github.com/krostar/nebulo-server/user"
 )
 
+var (
+	// ErrNotFound is throw when a message is not found
+	ErrNotFound = errors.New("message not found")
+	// ErrReceiversMismatch is throw when a message is not created or updated for exactly all its receivers
+	ErrReceiversMismatch = errors.New("receivers does not match the receivers of the message")
+	// ErrClientIDExists is throw when a sender already created a message with the same client identifier
+	ErrClientIDExists = errors.New("a message with this client identifier already exists")
+)
+
+// Range select the messages of a channel posted between two dates and/or
+// between two messages, every bounds are inclusive and a zero value bound is ignored
+type Range struct {
+	From   time.Time
+	To     time.Time
+	FromID string
+	ToID   string
+}
+
+// IsEmpty return true if no bounds are set
+func (r Range) IsEmpty() bool {
+	return r.From.IsZero() && r.To.IsZero() && r.FromID == "" && r.ToID == ""
+}
+
+// Page select the messages of a channel after and/or before a sequence number,
+// a zero value bound is ignored
+type Page struct {
+	After  int64
+	Before int64
+	Limit  int
+}
+
+// Receipt is the delivery and read state of a message for one of its receivers
+type Receipt struct {
+	Message   string     `json:"id" gorm:"column:public_id"`
+	Receiver  string     `json:"receiver_fingerprint" gorm:"column:key_fingerprint"`
+	Delivered *time.Time `json:"delivered" gorm:"column:delivered"`
+	Seen      *time.Time `json:"seen" gorm:"column:seen"`
+}
+
+// Expiration return the expiration date of a message posted now with the given ttl
+// in the channel, or nil if it never expires; the shortest non-zero ttl wins
+func Expiration(now time.Time, chann channel.Channel, ttl time.Duration) *time.Time {
+	if channelTTL := time.Duration(chann.MessagesTTL) * time.Second; channelTTL > 0 && (ttl <= 0 || channelTTL < ttl) {
+		ttl = channelTTL
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	expires := now.Add(ttl)
+	return &expires
+}
+
 type SecureMsg struct {
 	Message   []byte `json:"message"`
 	Keys      []byte `json:"keys"`
@@ -19,7 +72,14 @@ type Message struct {
 	SenderID   int `json:"-" gorm:"column:sender_id; not null"`
 	ReceiverID int `json:"-" gorm:"column:receiver_id; not null"`
 
-	Message   []byte `json:"message" gorm:"column:message; type:blob; not null"`
+	// PublicID and Sequence are shared by every receivers copies of the same message,
+	// the sequence number increase with each message posted in the channel
+	PublicID string `json:"id" gorm:"column:public_id; size:22; not null"`
+	Sequence int64  `json:"sequence" gorm:"column:sequence; not null"`
+	// ClientID is an optional identifier chosen by the sender to safely retry the message creation
+	ClientID *string `json:"client_id,omitempty" gorm:"column:client_id; size:36" sql:"DEFAULT:NULL"`
+
+	Message   []byte `json:"message" gorm:"column:message; not null"`
 	Keys      []byte `json:"keys" gorm:"column:keys; size:256; not null"`
 	Integrity []byte `json:"integrity" gorm:"column:integrity; size:32; not null"`
 
@@ -27,5 +87,51 @@ type Message struct {
 	Sender   user.User       `json:"sender" gorm:"column:ForeignKey:SenderID; save_associations:false"`
 	Receiver user.User       `json:"receiver" gorm:"column:ForeignKey:ReceiverID; save_associations:false"`
 	Posted   time.Time       `json:"posted" gorm:"column:posted; not null" sql:"DEFAULT:current_timestamp"`
-	Seen     time.Time       `json:"seen" gorm:"column:seen" sql:"DEFAULT:NULL"`
+	// Delivered is set once the receiver fetched the message, Seen once he read it
+	Delivered *time.Time `json:"delivered" gorm:"column:delivered" sql:"DEFAULT:NULL"`
+	Seen      *time.Time `json:"seen" gorm:"column:seen" sql:"DEFAULT:NULL"`
+	Edited    *time.Time `json:"edited" gorm:"column:edited" sql:"DEFAULT:NULL"`
+	Deleted   *time.Time `json:"deleted" gorm:"column:deleted" sql:"DEFAULT:NULL"`
+	// Expires is the date after which the message is no longer available, if any
+	Expires *time.Time `json:"expires" gorm:"column:expires" sql:"DEFAULT:NULL"`
+}
+
+// Compact is a lighter representation of a message, the channel and the
+// sender are referenced by their public identifier and fingerprint instead of being embedded
+type Compact struct {
+	PublicID  string  `json:"id"`
+	Sequence  int64   `json:"sequence"`
+	ClientID  *string `json:"client_id,omitempty"`
+	Channel   string  `json:"channel_id"`
+	Sender    string  `json:"sender_fingerprint"`
+	Message   []byte  `json:"message"`
+	Keys      []byte  `json:"keys"`
+	Integrity []byte  `json:"integrity"`
+
+	Posted    time.Time  `json:"posted"`
+	Delivered *time.Time `json:"delivered"`
+	Seen      *time.Time `json:"seen"`
+	Edited    *time.Time `json:"edited"`
+	Deleted   *time.Time `json:"deleted"`
+	Expires   *time.Time `json:"expires"`
+}
+
+// Compact return the compact representation of the message
+func (m *Message) Compact() *Compact {
+	return &Compact{
+		PublicID:  m.PublicID,
+		Sequence:  m.Sequence,
+		ClientID:  m.ClientID,
+		Channel:   m.Channel.PublicID,
+		Sender:    m.Sender.FingerPrint,
+		Message:   m.Message,
+		Keys:      m.Keys,
+		Integrity: m.Integrity,
+		Posted:    m.Posted,
+		Delivered: m.Delivered,
+		Seen:      m.Seen,
+		Edited:    m.Edited,
+		Deleted:   m.Deleted,
+		Expires:   m.Expires,
+	}
 }
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/krostar/nebulo-server/channel"
+	"github.com/krostar/nebulo-server/message"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// Edit replace the content of every receivers copies of a message,
+// msgs contains the new content for each receiver ID and must match the existing copies
+func (p *Provider) Edit(sender user.User, chann channel.Channel, publicID string, msgs map[int]message.SecureMsg) (m []*message.Message, err error) {
+	p.Lock()
+	defer p.Unlock()
+
+	match := copiesOf(sender, chann, publicID, false)
+	if m = p.filter(match); len(m) == 0 {
+		return nil, message.ErrNotFound
+	}
+
+	if len(m) != len(msgs) {
+		return nil, message.ErrReceiversMismatch
+	}
+	for _, mm := range m {
+		if _, ok := msgs[mm.ReceiverID]; !ok {
+			return nil, message.ErrReceiversMismatch
+		}
+	}
+
+	now := time.Now().UTC()
+	edit := func(mm *message.Message) {
+		msg := msgs[mm.ReceiverID]
+		edited := now
+		mm.Message, mm.Keys, mm.Integrity, mm.Edited = msg.Message, msg.Keys, msg.Integrity, &edited
+	}
+	p.update(match, edit)
+	for _, mm := range m {
+		edit(mm)
+	}
+
+	return m, nil
+}
+
+// Delete remove every receivers copies of a message, a soft deletion only
+// wipe the content and keep the message to let clients know it has been deleted,
+// a soft deleted message can still be hard deleted
+func (p *Provider) Delete(sender user.User, chann channel.Channel, publicID string, hard bool) (m []*message.Message, err error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if m = p.filter(copiesOf(sender, chann, publicID, hard)); len(m) == 0 {
+		return nil, message.ErrNotFound
+	}
+
+	match := copiesOf(sender, chann, publicID, true)
+	if hard {
+		p.remove(match)
+		return m, nil
+	}
+
+	now := time.Now().UTC()
+	wipe := func(mm *message.Message) {
+		deleted := now
+		mm.Message, mm.Keys, mm.Integrity, mm.Deleted = []byte{}, []byte{}, []byte{}, &deleted
+	}
+	p.update(match, wipe)
+	for _, mm := range m {
+		wipe(mm)
+	}
+
+	return m, nil
+}
+
+// copiesOf match the receivers copies of a message, soft deleted copies are ignored unless withDeleted is set
+func copiesOf(sender user.User, chann channel.Channel, publicID string, withDeleted bool) func(mm *message.Message) bool {
+	return func(mm *message.Message) bool {
+		return mm.ChannelID == chann.ID && mm.SenderID == sender.ID && mm.PublicID == publicID &&
+			(withDeleted || mm.Deleted == nil)
+	}
+}
@@ -0,0 +1,206 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/krostar/nebulo-server/channel"
+	"github.com/krostar/nebulo-server/message"
+	"github.com/krostar/nebulo-server/message/provider"
+	"github.com/krostar/nebulo-server/provider/memory"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// Provider implements the methods needed to manage messages
+// with every rows kept in memory
+type Provider struct {
+	*memory.Store
+	provider.Provider
+}
+
+// Init initialize a memory provider and set it as the used provider
+func Init() error {
+	if memory.S == nil {
+		return memory.ErrStoreIsNil
+	}
+
+	provider.P = &Provider{Store: memory.S}
+	return nil
+}
+
+// Create insert the receivers copies of a message at once, msgs contains the
+// content for each receiver ID and must match exactly the members who joined the channel,
+// clientID is optional and unique for a sender, the message expires after the shortest
+// of ttl and the channel messages ttl, a zero ttl is ignored
+func (p *Provider) Create(sender user.User, chann channel.Channel, publicID string, clientID string, ttl time.Duration, msgs map[int]message.SecureMsg) (m []*message.Message, err error) {
+	p.Lock()
+	defer p.Unlock()
+
+	var members []int
+	for _, um := range p.Memberships {
+		if um.ChannelID == chann.ID && !um.IsPending() {
+			members = append(members, um.UserID)
+		}
+	}
+	if len(members) != len(msgs) {
+		return nil, message.ErrReceiversMismatch
+	}
+	for _, id := range members {
+		if _, ok := msgs[id]; !ok {
+			return nil, message.ErrReceiversMismatch
+		}
+	}
+
+	var cID *string
+	if clientID != "" {
+		for _, mm := range p.Messages {
+			if mm.SenderID == sender.ID && mm.ClientID != nil && *mm.ClientID == clientID {
+				return nil, message.ErrClientIDExists
+			}
+		}
+		cID = &clientID
+	}
+
+	var sequence int64
+	found := false
+	for i := range p.Channels {
+		if p.Channels[i].ID == chann.ID {
+			p.Channels[i].MessageSequence++
+			sequence, found = p.Channels[i].MessageSequence, true
+		}
+	}
+	if !found {
+		return nil, channel.ErrNotFound
+	}
+
+	now := time.Now().UTC()
+	expires := message.Expiration(now, chann, ttl)
+
+	m = make([]*message.Message, 0, len(members))
+	for _, receiverID := range members {
+		msg := msgs[receiverID]
+		mm := message.Message{
+			ID:         p.NextID("messages"),
+			ChannelID:  chann.ID,
+			SenderID:   sender.ID,
+			ReceiverID: receiverID,
+			PublicID:   publicID,
+			Sequence:   sequence,
+			ClientID:   cID,
+			Posted:     now,
+			Expires:    expires,
+
+			Message:   msg.Message,
+			Keys:      msg.Keys,
+			Integrity: msg.Integrity,
+		}
+		p.Messages = append(p.Messages, mm)
+		m = append(m, &mm)
+	}
+
+	return m, nil
+}
+
+// FindByClientID return the receivers copies of the message the sender created with the client identifier
+func (p *Provider) FindByClientID(sender user.User, clientID string) (m []*message.Message, err error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	m = p.filter(func(mm *message.Message) bool {
+		return mm.SenderID == sender.ID && mm.ClientID != nil && *mm.ClientID == clientID
+	})
+	if len(m) == 0 {
+		return nil, message.ErrNotFound
+	}
+	return m, nil
+}
+
+// List return at most page.Limit receiver copies of the channel messages, ordered by sequence number.
+// The messages right after page.After are returned if it is set, otherwise the messages right before
+// page.Before, or the last messages of the channel; hasMore is true if more messages match the page bounds
+func (p *Provider) List(receiver user.User, chann channel.Channel, page message.Page) (m []*message.Message, hasMore bool, err error) {
+	if page.Limit <= 0 {
+		return nil, false, nil
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	// expired messages may not be purged yet
+	now := time.Now().UTC()
+	m = p.filter(func(mm *message.Message) bool {
+		return mm.ChannelID == chann.ID && mm.ReceiverID == receiver.ID &&
+			(mm.Expires == nil || mm.Expires.After(now)) &&
+			(page.After <= 0 || mm.Sequence > page.After) &&
+			(page.Before <= 0 || mm.Sequence < page.Before)
+	})
+	sortBySequence(m)
+
+	// the page starts right after page.After, or ends with the last matching message
+	if len(m) > page.Limit {
+		hasMore = true
+		if page.After > 0 {
+			m = m[:page.Limit]
+		} else {
+			m = m[len(m)-page.Limit:]
+		}
+	}
+
+	senders := make(map[int]user.User, len(p.Users))
+	for _, u := range p.Users {
+		senders[u.ID] = u
+	}
+	for _, mm := range m {
+		mm.Channel = chann
+		mm.Sender = senders[mm.SenderID]
+		mm.Receiver = receiver
+	}
+
+	p.markDelivered(m, now)
+	return m, hasMore, nil
+}
+
+// filter return a copy of the messages matching the predicate, ordered by ID
+func (p *Provider) filter(match func(mm *message.Message) bool) (m []*message.Message) {
+	m = []*message.Message{}
+	for i := range p.Messages {
+		if match(&p.Messages[i]) {
+			mm := p.Messages[i]
+			m = append(m, &mm)
+		}
+	}
+	return m
+}
+
+// update apply the change on every stored messages matching the predicate
+func (p *Provider) update(match func(mm *message.Message) bool, change func(mm *message.Message)) {
+	for i := range p.Messages {
+		if match(&p.Messages[i]) {
+			change(&p.Messages[i])
+		}
+	}
+}
+
+// remove delete every stored messages matching the predicate and return how many were deleted
+func (p *Provider) remove(match func(mm *message.Message) bool) (deleted int64) {
+	messages := p.Messages[:0]
+	for _, mm := range p.Messages {
+		if match(&mm) {
+			deleted++
+			continue
+		}
+		messages = append(messages, mm)
+	}
+	p.Messages = messages
+	return deleted
+}
+
+// sortBySequence order messages by sequence number, then by ID
+func sortBySequence(m []*message.Message) {
+	sort.Slice(m, func(i, j int) bool {
+		if m[i].Sequence != m[j].Sequence {
+			return m[i].Sequence < m[j].Sequence
+		}
+		return m[i].ID < m[j].ID
+	})
+}
@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/krostar/nebulo-server/channel"
+	"github.com/krostar/nebulo-server/message"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// DeleteRange remove the receiver copies of the channel messages within the range
+// and return the number of deleted messages
+func (p *Provider) DeleteRange(receiver user.User, chann channel.Channel, r message.Range) (deleted int64, err error) {
+	p.Lock()
+	defer p.Unlock()
+
+	// like in sql, a bound on an unknown message match nothing
+	fromID, toID := p.rangeBound(receiver, chann, r.FromID), p.rangeBound(receiver, chann, r.ToID)
+	if (r.FromID != "" && fromID == 0) || (r.ToID != "" && toID == 0) {
+		return 0, nil
+	}
+
+	return p.remove(func(mm *message.Message) bool {
+		return mm.ChannelID == chann.ID && mm.ReceiverID == receiver.ID &&
+			(r.From.IsZero() || !mm.Posted.Before(r.From.UTC())) &&
+			(r.To.IsZero() || !mm.Posted.After(r.To.UTC())) &&
+			(fromID == 0 || mm.ID >= fromID) &&
+			(toID == 0 || mm.ID <= toID)
+	}), nil
+}
+
+// rangeBound return the ID of the receiver copy of a message, or 0
+func (p *Provider) rangeBound(receiver user.User, chann channel.Channel, publicID string) int {
+	if publicID == "" {
+		return 0
+	}
+	for _, mm := range p.Messages {
+		if mm.ChannelID == chann.ID && mm.ReceiverID == receiver.ID && mm.PublicID == publicID {
+			return mm.ID
+		}
+	}
+	return 0
+}
+
+// DeleteExpired remove every messages that expired before now
+func (p *Provider) DeleteExpired(now time.Time) (deleted int64, err error) {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.remove(func(mm *message.Message) bool {
+		return mm.Expires != nil && !mm.Expires.After(now)
+	}), nil
+}
@@ -0,0 +1,141 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/krostar/nebulo-server/channel"
+	"github.com/krostar/nebulo-server/message"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// MarkSeen set the seen date of the receiver copies of the messages,
+// it return the messages that were not already seen
+func (p *Provider) MarkSeen(receiver user.User, chann channel.Channel, publicIDs []string) (m []*message.Message, err error) {
+	if len(publicIDs) == 0 {
+		return nil, nil
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	return p.markSeen(receiverCopies(receiver, chann, publicIDs)), nil
+}
+
+// MarkSeenUpTo set the seen date of every receiver copies of the messages
+// up to the given one included, it return the messages that were not already seen
+func (p *Provider) MarkSeenUpTo(receiver user.User, chann channel.Channel, publicID string) (m []*message.Message, err error) {
+	p.Lock()
+	defer p.Unlock()
+
+	cursor := p.rangeBound(receiver, chann, publicID)
+	if cursor == 0 {
+		return nil, message.ErrNotFound
+	}
+
+	return p.markSeen(func(mm *message.Message) bool {
+		return mm.ChannelID == chann.ID && mm.ReceiverID == receiver.ID && mm.ID <= cursor
+	}), nil
+}
+
+func (p *Provider) markSeen(match func(mm *message.Message) bool) (m []*message.Message) {
+	unseen := func(mm *message.Message) bool {
+		return match(mm) && mm.Seen == nil
+	}
+	if m = p.filter(unseen); len(m) == 0 {
+		return m
+	}
+
+	// a seen message has obviously been delivered
+	now := time.Now().UTC()
+	see := func(mm *message.Message) {
+		seen := now
+		if mm.Delivered == nil {
+			mm.Delivered = &seen
+		}
+		mm.Seen = &seen
+	}
+	p.update(unseen, see)
+	for _, mm := range m {
+		see(mm)
+	}
+
+	return m
+}
+
+// Receipts return the delivery and read state of a message for each of its receivers
+func (p *Provider) Receipts(sender user.User, chann channel.Channel, publicID string) (r []*message.Receipt, err error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	r = []*message.Receipt{}
+	for _, mm := range p.filter(copiesOf(sender, chann, publicID, true)) {
+		for _, u := range p.Users {
+			if u.ID == mm.ReceiverID {
+				r = append(r, &message.Receipt{
+					Message:   mm.PublicID,
+					Receiver:  u.FingerPrint,
+					Delivered: mm.Delivered,
+					Seen:      mm.Seen,
+				})
+			}
+		}
+	}
+	if len(r) == 0 {
+		return nil, message.ErrNotFound
+	}
+
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].Receiver < r[j].Receiver
+	})
+	return r, nil
+}
+
+// markDelivered set the delivery date of the messages that were not already delivered
+func (p *Provider) markDelivered(m []*message.Message, now time.Time) {
+	for _, mm := range m {
+		if mm.Delivered != nil {
+			continue
+		}
+		delivered := now
+		mm.Delivered = &delivered
+
+		id := mm.ID
+		p.update(func(stored *message.Message) bool {
+			return stored.ID == id && stored.Delivered == nil
+		}, func(stored *message.Message) {
+			stored.Delivered = &delivered
+		})
+	}
+}
+
+// Acknowledge delete the receiver copies of the messages once the receiver got them,
+// it return the deleted copies
+func (p *Provider) Acknowledge(receiver user.User, chann channel.Channel, publicIDs []string) (m []*message.Message, err error) {
+	if len(publicIDs) == 0 {
+		return nil, nil
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	match := receiverCopies(receiver, chann, publicIDs)
+	m = p.filter(match)
+	p.remove(match)
+	return m, nil
+}
+
+// receiverCopies match the receiver copies of the messages
+func receiverCopies(receiver user.User, chann channel.Channel, publicIDs []string) func(mm *message.Message) bool {
+	return func(mm *message.Message) bool {
+		if mm.ChannelID != chann.ID || mm.ReceiverID != receiver.ID {
+			return false
+		}
+		for _, publicID := range publicIDs {
+			if mm.PublicID == publicID {
+				return true
+			}
+		}
+		return false
+	}
+}
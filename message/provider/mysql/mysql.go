@@ -2,7 +2,6 @@ package sqlite
 
 import (
 	gp "github.com/krostar/nebulo-golib/provider"
-	"github.com/krostar/nebulo-server/message"
 	"github.com/krostar/nebulo-server/message/provider"
 	dp "github.com/krostar/nebulo-server/message/provider/sql"
 )
@@ -25,17 +24,3 @@ func Init() error {
 	provider.P = p
 	return nil
 }
-
-// DropTables delete all the channels tables
-func (p *Provider) DropTables() (err error) {
-	m := &message.Message{}
-
-	err = gp.RP.DB.Exec("SET FOREIGN_KEY_CHECKS=0;").Error
-	if err == nil {
-		err = p.DB.DropTableIfExists(m).Error
-	}
-	if err == nil {
-		err = gp.RP.DB.Exec("SET FOREIGN_KEY_CHECKS=1;").Error
-	}
-	return err
-}
@@ -3,8 +3,6 @@ package provider
 import (
 	"time"
 
-	gp "github.com/krostar/nebulo-golib/provider"
-
 	"github.com/krostar/nebulo-server/channel"
 	"github.com/krostar/nebulo-server/message"
 	"github.com/krostar/nebulo-server/user"
@@ -12,10 +10,20 @@ import (
 
 // Provider contains all the methods needed to manage channels
 type Provider interface {
-	gp.TablesManagement
+	Create(sender user.User, chann channel.Channel, publicID string, clientID string, ttl time.Duration, msgs map[int]message.SecureMsg) (m []*message.Message, err error)
+	FindByClientID(sender user.User, clientID string) (m []*message.Message, err error)
+	List(receiver user.User, chann channel.Channel, page message.Page) (m []*message.Message, hasMore bool, err error)
+
+	Edit(sender user.User, chann channel.Channel, publicID string, msgs map[int]message.SecureMsg) (m []*message.Message, err error)
+	Delete(sender user.User, chann channel.Channel, publicID string, hard bool) (m []*message.Message, err error)
+	DeleteRange(receiver user.User, chann channel.Channel, r message.Range) (deleted int64, err error)
+
+	MarkSeen(receiver user.User, chann channel.Channel, publicIDs []string) (m []*message.Message, err error)
+	MarkSeenUpTo(receiver user.User, chann channel.Channel, publicID string) (m []*message.Message, err error)
+	Receipts(sender user.User, chann channel.Channel, publicID string) (r []*message.Receipt, err error)
+	Acknowledge(receiver user.User, chann channel.Channel, publicIDs []string) (m []*message.Message, err error)
 
-	Create(sender user.User, receiver user.User, chann channel.Channel, msg message.SecureMsg) (m *message.Message, err error)
-	List(receiver user.User, chann channel.Channel, lastRead time.Time, limit int) (m []*message.Message, err error)
+	DeleteExpired(now time.Time) (deleted int64, err error)
 }
 
 // P is the selected provider
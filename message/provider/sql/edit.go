@@ -0,0 +1,111 @@
+package sql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/krostar/nebulo-server/channel"
+	"github.com/krostar/nebulo-server/message"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// Edit replace the content of every receivers copies of a message,
+// msgs contains the new content for each receiver ID and must match the existing copies
+func (p *Provider) Edit(sender user.User, chann channel.Channel, publicID string, msgs map[int]message.SecureMsg) (m []*message.Message, err error) {
+	if m, err = p.findCopies(sender, chann, publicID, false); err != nil {
+		return nil, err
+	}
+
+	if len(m) != len(msgs) {
+		return nil, message.ErrReceiversMismatch
+	}
+	for _, mm := range m {
+		if _, ok := msgs[mm.ReceiverID]; !ok {
+			return nil, message.ErrReceiversMismatch
+		}
+	}
+
+	now := time.Now().UTC()
+	tx := p.DB.Begin()
+	if err = tx.Error; err != nil {
+		return nil, fmt.Errorf("unable to start transaction: %v", err)
+	}
+	for _, mm := range m {
+		msg := msgs[mm.ReceiverID]
+		if err = tx.Model(mm).Updates(map[string]interface{}{
+			"message":   msg.Message,
+			"keys":      msg.Keys,
+			"integrity": msg.Integrity,
+			"edited":    now,
+		}).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("unable to update message %d: %v", mm.ID, err)
+		}
+		mm.Message, mm.Keys, mm.Integrity, mm.Edited = msg.Message, msg.Keys, msg.Integrity, &now
+	}
+	if err = tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("unable to commit message edition: %v", err)
+	}
+
+	return m, nil
+}
+
+// Delete remove every receivers copies of a message, a soft deletion only
+// wipe the content and keep the message to let clients know it has been deleted,
+// a soft deleted message can still be hard deleted
+func (p *Provider) Delete(sender user.User, chann channel.Channel, publicID string, hard bool) (m []*message.Message, err error) {
+	if m, err = p.findCopies(sender, chann, publicID, hard); err != nil {
+		return nil, err
+	}
+
+	where := &message.Message{
+		ChannelID: chann.ID,
+		SenderID:  sender.ID,
+		PublicID:  publicID,
+	}
+
+	if hard {
+		if err = p.DB.Where(where).Delete(&message.Message{}).Error; err != nil {
+			return nil, fmt.Errorf("unable to delete message: %v", err)
+		}
+		return m, nil
+	}
+
+	now := time.Now().UTC()
+	if err = p.DB.Model(&message.Message{}).Where(where).Updates(map[string]interface{}{
+		"message":   []byte{},
+		"keys":      []byte{},
+		"integrity": []byte{},
+		"deleted":   now,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("unable to wipe message: %v", err)
+	}
+	for _, mm := range m {
+		mm.Message, mm.Keys, mm.Integrity, mm.Deleted = []byte{}, []byte{}, []byte{}, &now
+	}
+
+	return m, nil
+}
+
+// findCopies return the receivers copies of a message, soft deleted copies are ignored unless withDeleted is set
+func (p *Provider) findCopies(sender user.User, chann channel.Channel, publicID string, withDeleted bool) (m []*message.Message, err error) {
+	where := &message.Message{
+		ChannelID: chann.ID,
+		SenderID:  sender.ID,
+		PublicID:  publicID,
+	}
+
+	query := p.DB.Where(where)
+	if !withDeleted {
+		query = query.Where("deleted IS NULL")
+	}
+
+	m = []*message.Message{}
+	if err = query.Find(&m).Error; err != nil {
+		return nil, fmt.Errorf("unable to select message in db: %v", err)
+	}
+	if len(m) == 0 {
+		return nil, message.ErrNotFound
+	}
+	return m, nil
+}
@@ -0,0 +1,53 @@
+package sql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/krostar/nebulo-server/channel"
+	"github.com/krostar/nebulo-server/message"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// the bounds of an ID range are resolved to the receiver copies ids in the deletion query itself,
+// the aggregate in the derived table force mysql to materialize it, otherwise mysql
+// refuse to select from the table being deleted
+const rangeBoundQuery = "(SELECT bound FROM (SELECT MIN(id) AS bound FROM messages WHERE channel_id = ? AND receiver_id = ? AND public_id = ?) AS range_bound)"
+
+// DeleteRange remove the receiver copies of the channel messages within the range,
+// it runs as a single statement and return the number of deleted messages
+func (p *Provider) DeleteRange(receiver user.User, chann channel.Channel, r message.Range) (deleted int64, err error) {
+	where := &message.Message{
+		ChannelID:  chann.ID,
+		ReceiverID: receiver.ID,
+	}
+
+	query := p.DB.Where(where)
+	if !r.From.IsZero() {
+		query = query.Where("posted >= ?", r.From.UTC())
+	}
+	if !r.To.IsZero() {
+		query = query.Where("posted <= ?", r.To.UTC())
+	}
+	if r.FromID != "" {
+		query = query.Where("id >= "+rangeBoundQuery, chann.ID, receiver.ID, r.FromID)
+	}
+	if r.ToID != "" {
+		query = query.Where("id <= "+rangeBoundQuery, chann.ID, receiver.ID, r.ToID)
+	}
+
+	query = query.Delete(&message.Message{})
+	if err = query.Error; err != nil {
+		return 0, fmt.Errorf("unable to delete messages range: %v", err)
+	}
+	return query.RowsAffected, nil
+}
+
+// DeleteExpired remove every messages that expired before now
+func (p *Provider) DeleteExpired(now time.Time) (deleted int64, err error) {
+	query := p.DB.Where("expires <= ?", now.UTC()).Delete(&message.Message{})
+	if err = query.Error; err != nil {
+		return 0, fmt.Errorf("unable to delete expired messages: %v", err)
+	}
+	return query.RowsAffected, nil
+}
@@ -0,0 +1,159 @@
+package sql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/krostar/nebulo-server/channel"
+	"github.com/krostar/nebulo-server/message"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// MarkSeen set the seen date of the receiver copies of the messages,
+// it return the messages that were not already seen
+func (p *Provider) MarkSeen(receiver user.User, chann channel.Channel, publicIDs []string) (m []*message.Message, err error) {
+	if len(publicIDs) == 0 {
+		return nil, nil
+	}
+	return p.markSeen(p.receiverCopies(receiver, chann).Where("public_id IN (?)", publicIDs))
+}
+
+// MarkSeenUpTo set the seen date of every receiver copies of the messages
+// up to the given one included, it return the messages that were not already seen
+func (p *Provider) MarkSeenUpTo(receiver user.User, chann channel.Channel, publicID string) (m []*message.Message, err error) {
+	cursor := &message.Message{}
+	query := p.receiverCopies(receiver, chann).Where("public_id = ?", publicID).First(cursor)
+	if query.RecordNotFound() {
+		return nil, message.ErrNotFound
+	} else if err = query.Error; err != nil {
+		return nil, fmt.Errorf("unable to find message %q: %v", publicID, err)
+	}
+
+	return p.markSeen(p.receiverCopies(receiver, chann).Where("id <= ?", cursor.ID))
+}
+
+func (p *Provider) markSeen(query *gorm.DB) (m []*message.Message, err error) {
+	query = query.Where("seen IS NULL")
+
+	m = []*message.Message{}
+	if err = query.Find(&m).Error; err != nil {
+		return nil, fmt.Errorf("unable to select messages in db: %v", err)
+	}
+	if len(m) == 0 {
+		return m, nil
+	}
+
+	ids := make([]int, len(m))
+	for i, mm := range m {
+		ids[i] = mm.ID
+	}
+
+	// a seen message has obviously been delivered
+	now := time.Now().UTC()
+	if err = p.DB.Model(&message.Message{}).Where("id IN (?)", ids).Updates(map[string]interface{}{
+		"delivered": gorm.Expr("COALESCE(delivered, ?)", now),
+		"seen":      now,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("unable to mark messages as seen: %v", err)
+	}
+	for _, mm := range m {
+		if mm.Delivered == nil {
+			mm.Delivered = &now
+		}
+		mm.Seen = &now
+	}
+
+	return m, nil
+}
+
+// Receipts return the delivery and read state of a message for each of its receivers
+func (p *Provider) Receipts(sender user.User, chann channel.Channel, publicID string) (r []*message.Receipt, err error) {
+	r = []*message.Receipt{}
+	if err = p.DB.Table("messages").
+		Select("messages.public_id, users.key_fingerprint, messages.delivered, messages.seen").
+		Joins("JOIN users ON users.id = messages.receiver_id").
+		Where("messages.channel_id = ? AND messages.sender_id = ? AND messages.public_id = ?", chann.ID, sender.ID, publicID).
+		Order("users.key_fingerprint").
+		Scan(&r).Error; err != nil {
+		return nil, fmt.Errorf("unable to select receipts in db: %v", err)
+	}
+	if len(r) == 0 {
+		return nil, message.ErrNotFound
+	}
+	return r, nil
+}
+
+// markDelivered set the delivery date of the messages that were not already delivered
+func (p *Provider) markDelivered(m []*message.Message) (err error) {
+	var ids []int
+	for _, mm := range m {
+		if mm.Delivered == nil {
+			ids = append(ids, mm.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	if err = p.DB.Model(&message.Message{}).Where("id IN (?)", ids).Update("delivered", now).Error; err != nil {
+		return fmt.Errorf("unable to mark messages as delivered: %v", err)
+	}
+	for _, mm := range m {
+		if mm.Delivered == nil {
+			mm.Delivered = &now
+		}
+	}
+	return nil
+}
+
+func (p *Provider) receiverCopies(receiver user.User, chann channel.Channel) *gorm.DB {
+	return p.DB.Model(&message.Message{}).Where(&message.Message{
+		ChannelID:  chann.ID,
+		ReceiverID: receiver.ID,
+	})
+}
+
+// Acknowledge delete the receiver copies of the messages once the receiver got them,
+// it return the deleted copies
+func (p *Provider) Acknowledge(receiver user.User, chann channel.Channel, publicIDs []string) (m []*message.Message, err error) {
+	if len(publicIDs) == 0 {
+		return nil, nil
+	}
+
+	tx := p.DB.Begin()
+	if err = tx.Error; err != nil {
+		return nil, fmt.Errorf("unable to start transaction: %v", err)
+	}
+
+	where := &message.Message{
+		ChannelID:  chann.ID,
+		ReceiverID: receiver.ID,
+	}
+
+	m = []*message.Message{}
+	if err = tx.Where(where).Where("public_id IN (?)", publicIDs).Find(&m).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("unable to select messages in db: %v", err)
+	}
+	if len(m) == 0 {
+		tx.Rollback()
+		return m, nil
+	}
+
+	ids := make([]int, len(m))
+	for i, mm := range m {
+		ids[i] = mm.ID
+	}
+	if err = tx.Where("id IN (?)", ids).Delete(&message.Message{}).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("unable to delete acknowledged messages: %v", err)
+	}
+	if err = tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("unable to commit messages acknowledgement: %v", err)
+	}
+
+	return m, nil
+}
@@ -2,10 +2,9 @@ package sql
 
 import (
 	"fmt"
-	"math"
 	"time"
 
-	"github.com/krostar/nebulo-golib/log"
+	"github.com/jinzhu/gorm"
 	gp "github.com/krostar/nebulo-golib/provider"
 
 	"github.com/krostar/nebulo-server/channel"
@@ -21,59 +20,206 @@ type Provider struct {
 	provider.Provider
 }
 
-func (p *Provider) Create(sender user.User, receiver user.User, chann channel.Channel, msg message.SecureMsg) (m *message.Message, err error) {
-	m = &message.Message{
-		ChannelID:  chann.ID,
-		SenderID:   sender.ID,
-		ReceiverID: receiver.ID,
+// Create insert the receivers copies of a message in a single transaction, msgs contains the
+// content for each receiver ID and must match exactly the members who joined the channel,
+// clientID is optional and unique for a sender, the message expires after the shortest
+// of ttl and the channel messages ttl, a zero ttl is ignored
+func (p *Provider) Create(sender user.User, chann channel.Channel, publicID string, clientID string, ttl time.Duration, msgs map[int]message.SecureMsg) (m []*message.Message, err error) {
+	tx := p.DB.Begin()
+	if err = tx.Error; err != nil {
+		return nil, fmt.Errorf("unable to start transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
 
-		Message:   msg.Message,
-		Keys:      msg.Keys,
-		Integrity: msg.Integrity,
+	// the members are checked inside the transaction to not miss a member who joined meanwhile
+	var members []int
+	if err = tx.Model(&channel.UserMembership{}).
+		Where("channel_id = ? AND joined IS NOT NULL", chann.ID).
+		Pluck("user_id", &members).Error; err != nil {
+		return nil, fmt.Errorf("unable to get channel %d members: %v", chann.ID, err)
 	}
-	if err = p.DB.Create(m).Error; err != nil {
-		return nil, fmt.Errorf("unable to insert message: %v", err)
+	if len(members) != len(msgs) {
+		return nil, message.ErrReceiversMismatch
+	}
+	for _, id := range members {
+		if _, ok := msgs[id]; !ok {
+			return nil, message.ErrReceiversMismatch
+		}
+	}
+
+	sequence, err := nextSequence(tx, chann)
+	if err != nil {
+		return nil, err
 	}
 
+	var cID *string
+	if clientID != "" {
+		var used bool
+		if used, err = clientIDUsed(tx, sender, clientID); err != nil {
+			return nil, err
+		} else if used {
+			return nil, message.ErrClientIDExists
+		}
+		cID = &clientID
+	}
+
+	expires := message.Expiration(time.Now().UTC(), chann, ttl)
+
+	m = make([]*message.Message, 0, len(members))
+	for _, receiverID := range members {
+		msg := msgs[receiverID]
+		mm := &message.Message{
+			ChannelID:  chann.ID,
+			SenderID:   sender.ID,
+			ReceiverID: receiverID,
+			PublicID:   publicID,
+			Sequence:   sequence,
+			ClientID:   cID,
+			Expires:    expires,
+
+			Message:   msg.Message,
+			Keys:      msg.Keys,
+			Integrity: msg.Integrity,
+		}
+		if err = tx.Create(mm).Error; err != nil {
+			err = fmt.Errorf("unable to insert message for receiver %d: %v", receiverID, err)
+			return nil, p.clientIDConflict(tx, sender, clientID, err)
+		}
+		m = append(m, mm)
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("unable to commit message creation: %v", err)
+	}
+	return m, nil
+}
+
+// FindByClientID return the receivers copies of the message the sender created with the client identifier
+func (p *Provider) FindByClientID(sender user.User, clientID string) (m []*message.Message, err error) {
+	m = []*message.Message{}
+	if err = p.DB.Where("sender_id = ? AND client_id = ?", sender.ID, clientID).Find(&m).Error; err != nil {
+		return nil, fmt.Errorf("unable to select message in db: %v", err)
+	}
+	if len(m) == 0 {
+		return nil, message.ErrNotFound
+	}
 	return m, nil
 }
 
-func (p *Provider) List(receiver user.User, chann channel.Channel, lastRead time.Time, limit int) (m []*message.Message, err error) {
-	where := &message.Message{
+// clientIDUsed return true if the sender created a message with the client identifier
+func clientIDUsed(db *gorm.DB, sender user.User, clientID string) (used bool, err error) {
+	var count int
+	if err = db.Model(&message.Message{}).Where("sender_id = ? AND client_id = ?", sender.ID, clientID).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("unable to count messages with client id %q: %v", clientID, err)
+	}
+	return count > 0, nil
+}
+
+// clientIDConflict return message.ErrClientIDExists instead of err when the insertion failed because a concurrent
+// transaction used the same client identifier, the transaction is rolled back to look at the committed messages
+func (p *Provider) clientIDConflict(tx *gorm.DB, sender user.User, clientID string, err error) error {
+	if clientID == "" {
+		return err
+	}
+	tx.Rollback()
+	if used, errUsed := clientIDUsed(p.DB, sender, clientID); errUsed == nil && used {
+		return message.ErrClientIDExists
+	}
+	return err
+}
+
+// nextSequence increment and return the channel sequence number
+func nextSequence(tx *gorm.DB, chann channel.Channel) (sequence int64, err error) {
+	// the update lock the channel row until the end of the transaction
+	if err = tx.Model(&channel.Channel{}).Where("id = ?", chann.ID).
+		UpdateColumn("message_sequence", gorm.Expr("message_sequence + 1")).Error; err != nil {
+		return 0, fmt.Errorf("unable to increment channel %d sequence: %v", chann.ID, err)
+	}
+	c := &channel.Channel{}
+	if err = tx.Select("message_sequence").Where("id = ?", chann.ID).First(c).Error; err != nil {
+		return 0, fmt.Errorf("unable to get channel %d sequence: %v", chann.ID, err)
+	}
+	return c.MessageSequence, nil
+}
+
+// List return at most page.Limit receiver copies of the channel messages, ordered by sequence number.
+// The messages right after page.After are returned if it is set, otherwise the messages right before
+// page.Before, or the last messages of the channel; hasMore is true if more messages match the page bounds
+func (p *Provider) List(receiver user.User, chann channel.Channel, page message.Page) (m []*message.Message, hasMore bool, err error) {
+	if page.Limit <= 0 {
+		return nil, false, nil
+	}
+
+	// expired messages may not be purged yet
+	query := p.DB.Where(&message.Message{
 		ChannelID:  chann.ID,
 		ReceiverID: receiver.ID,
+	}).Where("expires IS NULL OR expires > ?", time.Now().UTC())
+	if page.After > 0 {
+		query = query.Where("sequence > ?", page.After)
 	}
-
-	if lastRead.IsZero() {
-		lastRead = time.Now()
+	if page.Before > 0 {
+		query = query.Where("sequence < ?", page.Before)
 	}
-	log.Debugln(lastRead, limit)
-	whereTime := "posted"
-	if limit < 0 {
-		whereTime += " < "
-	} else if limit > 0 {
-		whereTime += " > "
+	if page.After > 0 {
+		query = query.Order("sequence ASC, id ASC")
 	} else {
-		return nil, nil
+		query = query.Order("sequence DESC, id DESC")
+	}
+
+	// fetch one more message to know if there is more messages after this page
+	m = []*message.Message{}
+	if err = query.Limit(page.Limit + 1).Find(&m).Error; err != nil {
+		return nil, false, fmt.Errorf("unable to select messages in db: %v", err)
+	}
+	if len(m) > page.Limit {
+		m, hasMore = m[:page.Limit], true
+	}
+	if page.After <= 0 {
+		for i, j := 0, len(m)-1; i < j; i, j = i+1, j-1 {
+			m[i], m[j] = m[j], m[i]
+		}
+	}
+
+	if err = p.fillMessages(m, receiver, chann); err != nil {
+		return nil, false, err
 	}
-	whereTime += "?"
-	limit = int(math.Abs(float64(limit)))
-	m = make([]*message.Message, limit)
-	if err = p.DB.Where(where).Where(whereTime, lastRead).Limit(limit).Find(&m).Error; err != nil {
-		return nil, fmt.Errorf("unable to insert message: %v", err)
+
+	if err = p.markDelivered(m); err != nil {
+		return nil, false, err
 	}
 
+	return m, hasMore, nil
+}
+
+// fillMessages set the channel, the sender and the receiver of receiver copies of
+// messages posted in the channel, the channel is embedded as given and senders are loaded with a single query
+func (p *Provider) fillMessages(m []*message.Message, receiver user.User, chann channel.Channel) (err error) {
+	if len(m) == 0 {
+		return nil
+	}
+
+	sendersID := make([]int, 0, len(m))
 	for _, mm := range m {
-		if err = p.DB.Find(&mm.Channel, mm.ChannelID).Error; err != nil {
-			return nil, fmt.Errorf("unable to get channel for message %d: %v", mm.ChannelID, err)
-		}
-		if err = p.DB.Find(&mm.Sender, mm.SenderID).Error; err != nil {
-			return nil, fmt.Errorf("unable to get sender for message %d: %v", mm.SenderID, err)
-		}
-		if err = p.DB.Find(&mm.Receiver, mm.ReceiverID).Error; err != nil {
-			return nil, fmt.Errorf("unable to get receiver for message %d: %v", mm.ReceiverID, err)
-		}
+		sendersID = append(sendersID, mm.SenderID)
+	}
+	senders := []user.User{}
+	if err = p.DB.Where("id IN (?)", sendersID).Find(&senders).Error; err != nil {
+		return fmt.Errorf("unable to get senders of messages: %v", err)
+	}
+	sendersByID := make(map[int]user.User, len(senders))
+	for _, u := range senders {
+		sendersByID[u.ID] = u
 	}
 
-	return m, nil
+	for _, mm := range m {
+		mm.Channel = chann
+		mm.Sender = sendersByID[mm.SenderID]
+		mm.Receiver = receiver
+	}
+	return nil
 }
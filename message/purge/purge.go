@@ -0,0 +1,40 @@
+package purge
+
+import (
+	"time"
+
+	"github.com/krostar/nebulo-golib/log"
+
+	mp "github.com/krostar/nebulo-server/message/provider"
+)
+
+// Start periodically delete the expired messages until stop is called,
+// a non positive interval disable the purge
+func Start(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		log.Infoln("expired messages purge disabled")
+		return func() {}
+	}
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				deleted, err := mp.P.DeleteExpired(now)
+				if err != nil {
+					log.Errorf("unable to purge expired messages: %v", err)
+				} else if deleted > 0 {
+					log.Debugf("%d expired messages purged", deleted)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
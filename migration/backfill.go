@@ -0,0 +1,125 @@
+package migration
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/krostar/nebulo-server/identifier"
+)
+
+// FillPublicIDs return a step giving a random public identifier to the rows of the table which have none,
+// the identifiers are generated like the ones of the new rows
+func FillPublicIDs(table string) Step {
+	return func(tx *gorm.DB) (err error) {
+		var ids []int
+		if err = tx.Table(table).Where("public_id = ''").Order("id").Pluck("id", &ids).Error; err != nil {
+			return fmt.Errorf("unable to select %s without public id: %v", table, err)
+		}
+
+		for _, id := range ids {
+			publicID, err := identifier.New()
+			if err != nil {
+				return err
+			}
+			if err = tx.Exec("UPDATE "+table+" SET public_id = ? WHERE id = ?", publicID, id).Error; err != nil {
+				return fmt.Errorf("unable to set public id of %s %d: %v", table, id, err)
+			}
+		}
+		return nil
+	}
+}
+
+// NumberMessages return a step numbering the messages which have no sequence number in insertion order,
+// after the last number of their channel, and moving the sequence counter of the channels past them;
+// a message was stored once per receiver, the copies get the same public identifier and sequence number
+func NumberMessages() Step {
+	return func(tx *gorm.DB) (err error) {
+		var last []struct {
+			ChannelID int
+			Sequence  int64
+		}
+		if err = tx.Table("messages").Select("channel_id, MAX(sequence) AS sequence").Group("channel_id").Scan(&last).Error; err != nil {
+			return fmt.Errorf("unable to select messages last sequence numbers: %v", err)
+		}
+		sequences := make(map[int]int64, len(last))
+		for _, l := range last {
+			sequences[l.ChannelID] = l.Sequence
+		}
+
+		var copies []messageCopy
+		if err = tx.Table("messages").Select("id, channel_id, sender_id, receiver_id, public_id, posted").
+			Where("sequence = 0").Order("id").Scan(&copies).Error; err != nil {
+			return fmt.Errorf("unable to select messages without sequence number: %v", err)
+		}
+		for _, m := range groupCopies(copies) {
+			if m.publicID == "" {
+				if m.publicID, err = identifier.New(); err != nil {
+					return err
+				}
+			}
+			sequences[m.channelID]++
+			if err = tx.Exec("UPDATE messages SET public_id = ?, sequence = ? WHERE id IN (?)",
+				m.publicID, sequences[m.channelID], m.ids).Error; err != nil {
+				return fmt.Errorf("unable to set sequence number of message %d: %v", m.ids[0], err)
+			}
+		}
+
+		for channelID, sequence := range sequences {
+			if err = tx.Exec("UPDATE channels SET message_sequence = ? WHERE id = ? AND message_sequence < ?",
+				sequence, channelID, sequence).Error; err != nil {
+				return fmt.Errorf("unable to set sequence counter of channel %d: %v", channelID, err)
+			}
+		}
+		return nil
+	}
+}
+
+// messageCopy is the copy of a message stored for one of its receivers
+type messageCopy struct {
+	ID         int
+	ChannelID  int
+	SenderID   int
+	ReceiverID int
+	PublicID   string
+	Posted     time.Time
+}
+
+// groupedMessage is a message made of the copies of all its receivers
+type groupedMessage struct {
+	channelID int
+	publicID  string
+	ids       []int
+	receivers map[int]bool
+}
+
+// groupCopies group the copies of the messages in insertion order: the copies sharing a public identifier,
+// or without one, posted at the same time in the same channel by the same sender, are the same message
+// unless a receiver appears twice
+func groupCopies(copies []messageCopy) (messages []*groupedMessage) {
+	type key struct {
+		channelID int
+		senderID  int
+		posted    int64
+		publicID  string
+	}
+	current := make(map[key]*groupedMessage)
+
+	for _, c := range copies {
+		k := key{channelID: c.ChannelID, senderID: c.SenderID, publicID: c.PublicID}
+		if c.PublicID == "" {
+			k.posted = c.Posted.UnixNano()
+		}
+
+		m, ok := current[k]
+		if !ok || (c.PublicID == "" && m.receivers[c.ReceiverID]) {
+			m = &groupedMessage{channelID: c.ChannelID, publicID: c.PublicID, receivers: make(map[int]bool)}
+			current[k] = m
+			messages = append(messages, m)
+		}
+		m.ids = append(m.ids, c.ID)
+		m.receivers[c.ReceiverID] = true
+	}
+	return messages
+}
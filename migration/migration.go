@@ -0,0 +1,235 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrOutdated is throw when the database schema is older than the one expected
+	ErrOutdated = errors.New("database schema is outdated")
+	// ErrUnknownVersion is throw when the database schema is newer than the one expected
+	ErrUnknownVersion = errors.New("database schema is newer than the expected one")
+)
+
+// Migration is a numbered change of the database schema, Up apply it and Down revert it
+type Migration struct {
+	Version     int
+	Description string
+	Up          []string
+	Down        []string
+
+	// Adopt replace Up on a database created before the migrations existed,
+	// recognized by the existence of the AdoptTable table while no migration has been applied
+	AdoptTable string
+	Adopt      []Step
+}
+
+// Step is a change of the database done in the transaction of a migration
+type Step func(tx *gorm.DB) error
+
+// Exec return a step executing the statements
+func Exec(statements ...string) Step {
+	return func(tx *gorm.DB) (err error) {
+		for _, statement := range statements {
+			if err = tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// Status is the state of a migration in the database, Applied is nil if it is not applied
+type Status struct {
+	Migration
+	Applied *time.Time
+}
+
+// schemaVersion keeps track of the applied migrations
+type schemaVersion struct {
+	Version     int       `gorm:"column:version; primary_key; not null"`
+	Description string    `gorm:"column:description; size:255; not null"`
+	Applied     time.Time `gorm:"column:applied; not null"`
+}
+
+// TableName is the table name in database
+func (sv *schemaVersion) TableName() string {
+	return "schema_version"
+}
+
+// Migrator apply migrations on a database
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// M is the migrator of the selected provider, it is nil if the provider has no schema
+var M *Migrator
+
+// New return a migrator of the database, migrations must be ordered by version
+func New(db *gorm.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// Latest return the version of the last migration
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version return the version of the database schema, 0 if no migration has been applied
+func (m *Migrator) Version() (version int, err error) {
+	if err = m.createSchemaVersion(); err != nil {
+		return 0, err
+	}
+
+	var versions []int
+	if err = m.db.Model(&schemaVersion{}).Order("version DESC").Limit(1).Pluck("version", &versions).Error; err != nil {
+		return 0, fmt.Errorf("unable to get schema version: %v", err)
+	}
+	if len(versions) == 0 {
+		return 0, nil
+	}
+	return versions[0], nil
+}
+
+// Check return an error if the database schema is not the latest one
+func (m *Migrator) Check() (err error) {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	if latest := m.Latest(); version < latest {
+		return fmt.Errorf("%v: version %d, expected %d", ErrOutdated, version, latest)
+	} else if version > latest {
+		return fmt.Errorf("%v: version %d, expected %d", ErrUnknownVersion, version, latest)
+	}
+	return nil
+}
+
+// Status return the state of every migrations
+func (m *Migrator) Status() (status []Status, err error) {
+	if err = m.createSchemaVersion(); err != nil {
+		return nil, err
+	}
+
+	var applied []schemaVersion
+	if err = m.db.Find(&applied).Error; err != nil {
+		return nil, fmt.Errorf("unable to get applied migrations: %v", err)
+	}
+
+	status = make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		status[i].Migration = migration
+		for _, sv := range applied {
+			if sv.Version == migration.Version {
+				at := sv.Applied
+				status[i].Applied = &at
+			}
+		}
+	}
+	return status, nil
+}
+
+// Up apply the migrations up to the target version included, or every migrations if target is negative,
+// it return the applied migrations
+func (m *Migrator) Up(target int) (applied []Migration, err error) {
+	version, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	if target < 0 {
+		target = m.Latest()
+	}
+
+	for _, migration := range m.migrations {
+		if migration.Version <= version || migration.Version > target {
+			continue
+		}
+		steps := []Step{Exec(migration.Up...)}
+		if version == 0 && migration.Adopt != nil && m.db.HasTable(migration.AdoptTable) {
+			steps = migration.Adopt
+		}
+		if err = m.run(migration, steps, true); err != nil {
+			return applied, err
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+// Down revert the migrations down to the target version excluded, or only the last one if target is negative,
+// it return the reverted migrations
+func (m *Migrator) Down(target int) (reverted []Migration, err error) {
+	version, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	if target < 0 {
+		target = version - 1
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if migration.Version > version || migration.Version <= target {
+			continue
+		}
+		if err = m.run(migration, []Step{Exec(migration.Down...)}, false); err != nil {
+			return reverted, err
+		}
+		reverted = append(reverted, migration)
+	}
+	return reverted, nil
+}
+
+// run execute the steps of a migration and keep track of it in a single transaction,
+// databases which does not support transactional schema changes may be left half migrated
+func (m *Migrator) run(migration Migration, steps []Step, up bool) (err error) {
+	tx := m.db.Begin()
+	if err = tx.Error; err != nil {
+		return fmt.Errorf("unable to start transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, step := range steps {
+		if err = step(tx); err != nil {
+			return fmt.Errorf("migration %d failed: %v", migration.Version, err)
+		}
+	}
+
+	sv := &schemaVersion{Version: migration.Version, Description: migration.Description, Applied: time.Now().UTC()}
+	if up {
+		err = tx.Create(sv).Error
+	} else {
+		err = tx.Delete(sv).Error
+	}
+	if err != nil {
+		return fmt.Errorf("unable to track migration %d: %v", migration.Version, err)
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		return fmt.Errorf("unable to commit migration %d: %v", migration.Version, err)
+	}
+	return nil
+}
+
+func (m *Migrator) createSchemaVersion() (err error) {
+	if m.db.HasTable(&schemaVersion{}) {
+		return nil
+	}
+	if err = m.db.CreateTable(&schemaVersion{}).Error; err != nil {
+		return fmt.Errorf("unable to create schema version table: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package mysql
+
+import "github.com/krostar/nebulo-server/migration"
+
+// adoptSchema bring the tables created by the providers before the migrations existed to the initial schema,
+// it also replace the foreign key of the messages channel which referenced the users
+var adoptSchema = []migration.Step{
+	migration.Exec(
+		"ALTER TABLE users CONVERT TO CHARACTER SET utf8mb4,"+
+			"MODIFY signup DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,"+
+			"MODIFY login_first DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00',"+
+			"MODIFY login_last DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00'",
+
+		"ALTER TABLE channels CONVERT TO CHARACTER SET utf8mb4,"+
+			"ADD COLUMN public_id VARCHAR(22) NOT NULL DEFAULT '' AFTER creator_id,"+
+			"MODIFY created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,"+
+			"ADD COLUMN messages_ttl INT NOT NULL DEFAULT 0,"+
+			"ADD COLUMN message_sequence BIGINT NOT NULL DEFAULT 0",
+
+		"ALTER TABLE channel_memberships CONVERT TO CHARACTER SET utf8mb4,"+
+			"MODIFY invited DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,"+
+			"MODIFY joined DATETIME DEFAULT NULL",
+		// there was no invitation, every member joined the channel when it was created
+		"UPDATE channel_memberships SET joined = invited WHERE joined IS NULL",
+
+		"ALTER TABLE messages DROP FOREIGN KEY messages_channel_id_users_id_foreign",
+		"ALTER TABLE messages CONVERT TO CHARACTER SET utf8mb4,"+
+			"ADD COLUMN public_id VARCHAR(22) NOT NULL DEFAULT '' AFTER receiver_id,"+
+			"ADD COLUMN sequence BIGINT NOT NULL DEFAULT 0 AFTER public_id,"+
+			"ADD COLUMN client_id VARCHAR(36) DEFAULT NULL AFTER sequence,"+
+			"MODIFY message LONGBLOB NOT NULL,"+
+			"MODIFY posted DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,"+
+			"ADD COLUMN delivered DATETIME DEFAULT NULL AFTER posted,"+
+			"MODIFY seen DATETIME DEFAULT NULL,"+
+			"ADD COLUMN edited DATETIME DEFAULT NULL,"+
+			"ADD COLUMN deleted DATETIME DEFAULT NULL,"+
+			"ADD COLUMN expires DATETIME DEFAULT NULL,"+
+			"ADD CONSTRAINT fk_message_channel FOREIGN KEY (channel_id) REFERENCES channels (id) ON DELETE CASCADE ON UPDATE CASCADE",
+	),
+	migration.FillPublicIDs("channels"),
+	migration.NumberMessages(),
+	migration.Exec(
+		"ALTER TABLE channels ALTER COLUMN public_id DROP DEFAULT,"+
+			"ADD UNIQUE KEY uniq_channel_public_id (public_id)",
+		"ALTER TABLE messages ALTER COLUMN public_id DROP DEFAULT,"+
+			"ALTER COLUMN sequence DROP DEFAULT,"+
+			"ADD KEY idx_message_public_id (public_id),"+
+			"ADD UNIQUE KEY uniq_message_sequence (channel_id, receiver_id, sequence),"+
+			"ADD UNIQUE KEY uniq_message_client_id (sender_id, client_id, receiver_id)",
+
+		"CREATE TABLE attachments ("+
+			"id INT NOT NULL AUTO_INCREMENT,"+
+			"owner_id INT NOT NULL,"+
+			"channel_id INT NOT NULL,"+
+			"public_id VARCHAR(22) NOT NULL,"+
+			"size BIGINT NOT NULL,"+
+			"received BIGINT NOT NULL DEFAULT 0,"+
+			"created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,"+
+			"completed DATETIME DEFAULT NULL,"+
+			"PRIMARY KEY (id),"+
+			"UNIQUE KEY uniq_attachment_public_id (public_id),"+
+			"KEY idx_attachment_owner (owner_id),"+
+			"CONSTRAINT fk_attachment_channel FOREIGN KEY (channel_id) REFERENCES channels (id) ON DELETE CASCADE ON UPDATE CASCADE,"+
+			"CONSTRAINT fk_attachment_owner FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE"+
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+	),
+}
@@ -0,0 +1,101 @@
+package mysql
+
+import "github.com/krostar/nebulo-server/migration"
+
+// MySQL does not support transactional schema changes, a failed migration has to be cleaned by hand
+var initialSchema = migration.Migration{
+	Version:     1,
+	Description: "create users, channels, memberships, messages and attachments tables",
+	Up: []string{
+		"CREATE TABLE users (" +
+			"id INT NOT NULL AUTO_INCREMENT," +
+			"key_public_der VARBINARY(2000) NOT NULL," +
+			"key_fingerprint VARCHAR(51) NOT NULL," +
+			"display_name VARCHAR(42)," +
+			"signup DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP," +
+			"login_first DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00'," +
+			"login_last DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00'," +
+			"PRIMARY KEY (id)," +
+			"UNIQUE KEY uniq_user (key_public_der)" +
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+
+		"CREATE TABLE channels (" +
+			"id INT NOT NULL AUTO_INCREMENT," +
+			"creator_id INT NOT NULL," +
+			"public_id VARCHAR(22) NOT NULL," +
+			"name VARCHAR(64)," +
+			"created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP," +
+			"members_can_edit BOOLEAN NOT NULL DEFAULT FALSE," +
+			"members_can_invite BOOLEAN NOT NULL DEFAULT FALSE," +
+			"messages_ttl INT NOT NULL DEFAULT 0," +
+			"message_sequence BIGINT NOT NULL DEFAULT 0," +
+			"PRIMARY KEY (id)," +
+			"UNIQUE KEY uniq_channel (name, creator_id)," +
+			"UNIQUE KEY uniq_channel_public_id (public_id)," +
+			"CONSTRAINT fk_channel_creator FOREIGN KEY (creator_id) REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE" +
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+
+		"CREATE TABLE channel_memberships (" +
+			"id INT NOT NULL AUTO_INCREMENT," +
+			"channel_id INT NOT NULL," +
+			"user_id INT NOT NULL," +
+			"invited DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP," +
+			"joined DATETIME DEFAULT NULL," +
+			"PRIMARY KEY (id)," +
+			"UNIQUE KEY uniq_membership (channel_id, user_id)," +
+			"CONSTRAINT fk_membership_channel FOREIGN KEY (channel_id) REFERENCES channels (id) ON DELETE CASCADE ON UPDATE CASCADE," +
+			"CONSTRAINT fk_membership_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE" +
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+
+		"CREATE TABLE messages (" +
+			"id INT NOT NULL AUTO_INCREMENT," +
+			"channel_id INT NOT NULL," +
+			"sender_id INT NOT NULL," +
+			"receiver_id INT NOT NULL," +
+			"public_id VARCHAR(22) NOT NULL," +
+			"sequence BIGINT NOT NULL," +
+			"client_id VARCHAR(36) DEFAULT NULL," +
+			"message LONGBLOB NOT NULL," +
+			"`keys` VARBINARY(256) NOT NULL," +
+			"integrity VARBINARY(32) NOT NULL," +
+			"posted DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP," +
+			"delivered DATETIME DEFAULT NULL," +
+			"seen DATETIME DEFAULT NULL," +
+			"edited DATETIME DEFAULT NULL," +
+			"deleted DATETIME DEFAULT NULL," +
+			"expires DATETIME DEFAULT NULL," +
+			"PRIMARY KEY (id)," +
+			"KEY idx_message_public_id (public_id)," +
+			"UNIQUE KEY uniq_message_sequence (channel_id, receiver_id, sequence)," +
+			"UNIQUE KEY uniq_message_client_id (sender_id, client_id, receiver_id)," +
+			"CONSTRAINT fk_message_channel FOREIGN KEY (channel_id) REFERENCES channels (id) ON DELETE CASCADE ON UPDATE CASCADE," +
+			"CONSTRAINT fk_message_sender FOREIGN KEY (sender_id) REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE," +
+			"CONSTRAINT fk_message_receiver FOREIGN KEY (receiver_id) REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE" +
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+
+		"CREATE TABLE attachments (" +
+			"id INT NOT NULL AUTO_INCREMENT," +
+			"owner_id INT NOT NULL," +
+			"channel_id INT NOT NULL," +
+			"public_id VARCHAR(22) NOT NULL," +
+			"size BIGINT NOT NULL," +
+			"received BIGINT NOT NULL DEFAULT 0," +
+			"created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP," +
+			"completed DATETIME DEFAULT NULL," +
+			"PRIMARY KEY (id)," +
+			"UNIQUE KEY uniq_attachment_public_id (public_id)," +
+			"KEY idx_attachment_owner (owner_id)," +
+			"CONSTRAINT fk_attachment_channel FOREIGN KEY (channel_id) REFERENCES channels (id) ON DELETE CASCADE ON UPDATE CASCADE," +
+			"CONSTRAINT fk_attachment_owner FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE" +
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+	},
+	Down: []string{
+		"DROP TABLE attachments",
+		"DROP TABLE messages",
+		"DROP TABLE channel_memberships",
+		"DROP TABLE channels",
+		"DROP TABLE users",
+	},
+	AdoptTable: "users",
+	Adopt:      adoptSchema,
+}
@@ -0,0 +1,27 @@
+package mysql
+
+import "github.com/krostar/nebulo-server/migration"
+
+// certificates of deleted users are kept to be listed in the revocation lists
+var certificates = migration.Migration{
+	Version:     2,
+	Description: "create issued certificates table",
+	Up: []string{
+		"CREATE TABLE certificates (" +
+			"id INT NOT NULL AUTO_INCREMENT," +
+			"user_id INT NOT NULL," +
+			"serial VARCHAR(40) NOT NULL," +
+			"not_before DATETIME NOT NULL," +
+			"not_after DATETIME NOT NULL," +
+			"status VARCHAR(16) NOT NULL," +
+			"issued DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP," +
+			"revoked DATETIME DEFAULT NULL," +
+			"PRIMARY KEY (id)," +
+			"UNIQUE KEY uniq_certificate_serial (serial)," +
+			"KEY idx_certificate_user (user_id)" +
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+	},
+	Down: []string{
+		"DROP TABLE certificates",
+	},
+}
@@ -0,0 +1,23 @@
+package mysql
+
+import "github.com/krostar/nebulo-server/migration"
+
+var crls = migration.Migration{
+	Version:     3,
+	Description: "create certificate revocation lists table",
+	Up: []string{
+		"CREATE INDEX idx_certificate_status ON certificates (status, not_after)",
+
+		"CREATE TABLE crls (" +
+			"id INT NOT NULL AUTO_INCREMENT," +
+			"this_update DATETIME NOT NULL," +
+			"next_update DATETIME NOT NULL," +
+			"der MEDIUMBLOB NOT NULL," +
+			"PRIMARY KEY (id)" +
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+	},
+	Down: []string{
+		"DROP TABLE crls",
+		"DROP INDEX idx_certificate_status ON certificates",
+	},
+}
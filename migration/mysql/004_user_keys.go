@@ -0,0 +1,27 @@
+package mysql
+
+import "github.com/krostar/nebulo-server/migration"
+
+var userKeys = migration.Migration{
+	Version:     4,
+	Description: "create users key history table",
+	Up: []string{
+		"CREATE TABLE user_keys (" +
+			"id INT NOT NULL AUTO_INCREMENT," +
+			"user_id INT NOT NULL," +
+			"key_public_der VARBINARY(2000) NOT NULL," +
+			"key_fingerprint VARCHAR(51) NOT NULL," +
+			"added DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP," +
+			"retired DATETIME DEFAULT NULL," +
+			"PRIMARY KEY (id)," +
+			"UNIQUE KEY uniq_user_key (key_public_der)," +
+			"KEY idx_user_key_user (user_id)," +
+			"CONSTRAINT fk_user_key_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE" +
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+		"INSERT INTO user_keys (user_id, key_public_der, key_fingerprint, added) " +
+			"SELECT id, key_public_der, key_fingerprint, signup FROM users ORDER BY id",
+	},
+	Down: []string{
+		"DROP TABLE user_keys",
+	},
+}
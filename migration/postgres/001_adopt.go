@@ -0,0 +1,51 @@
+package postgres
+
+import "github.com/krostar/nebulo-server/migration"
+
+// adoptSchema bring the tables created by the providers before the migrations existed to the initial schema,
+// the columns, indexes and tables are only added when missing: the schema depends on the release which created them;
+// the memberships are kept as they are, the PostgreSQL provider was added after the invitations
+var adoptSchema = []migration.Step{
+	migration.Exec(
+		`ALTER TABLE channels
+			ADD COLUMN IF NOT EXISTS public_id VARCHAR(22) NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS messages_ttl INTEGER NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS message_sequence BIGINT NOT NULL DEFAULT 0`,
+
+		`ALTER TABLE messages
+			ADD COLUMN IF NOT EXISTS public_id VARCHAR(22) NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS sequence BIGINT NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS client_id VARCHAR(36) DEFAULT NULL,
+			ADD COLUMN IF NOT EXISTS delivered TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+			ADD COLUMN IF NOT EXISTS edited TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+			ADD COLUMN IF NOT EXISTS deleted TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+			ADD COLUMN IF NOT EXISTS expires TIMESTAMP WITH TIME ZONE DEFAULT NULL`,
+	),
+	migration.FillPublicIDs("channels"),
+	migration.NumberMessages(),
+	migration.Exec(
+		`ALTER TABLE channels ALTER COLUMN public_id DROP DEFAULT`,
+		`ALTER TABLE messages ALTER COLUMN public_id DROP DEFAULT, ALTER COLUMN sequence DROP DEFAULT`,
+
+		`CREATE UNIQUE INDEX IF NOT EXISTS uniq_user ON users (key_public_der)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS uniq_channel ON channels (name, creator_id)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS uniq_channel_public_id ON channels (public_id)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS uniq_membership ON channel_memberships (channel_id, user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_message_public_id ON messages (public_id)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS uniq_message_sequence ON messages (channel_id, receiver_id, sequence)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS uniq_message_client_id ON messages (sender_id, client_id, receiver_id)`,
+
+		`CREATE TABLE IF NOT EXISTS attachments (
+			id SERIAL PRIMARY KEY,
+			owner_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE,
+			channel_id INTEGER NOT NULL REFERENCES channels (id) ON DELETE CASCADE ON UPDATE CASCADE,
+			public_id VARCHAR(22) NOT NULL,
+			size BIGINT NOT NULL,
+			received BIGINT NOT NULL DEFAULT 0,
+			created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			completed TIMESTAMP WITH TIME ZONE DEFAULT NULL
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS uniq_attachment_public_id ON attachments (public_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_attachment_owner ON attachments (owner_id)`,
+	),
+}
@@ -0,0 +1,87 @@
+package postgres
+
+import "github.com/krostar/nebulo-server/migration"
+
+var initialSchema = migration.Migration{
+	Version:     1,
+	Description: "create users, channels, memberships, messages and attachments tables",
+	Up: []string{
+		`CREATE TABLE users (
+			id SERIAL PRIMARY KEY,
+			key_public_der BYTEA NOT NULL,
+			key_fingerprint VARCHAR(51) NOT NULL,
+			display_name VARCHAR(42),
+			signup TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			login_first TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT '1970-01-01 00:00:00+00',
+			login_last TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT '1970-01-01 00:00:00+00'
+		)`,
+		`CREATE UNIQUE INDEX uniq_user ON users (key_public_der)`,
+
+		`CREATE TABLE channels (
+			id SERIAL PRIMARY KEY,
+			creator_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE,
+			public_id VARCHAR(22) NOT NULL,
+			name VARCHAR(64),
+			created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			members_can_edit BOOLEAN NOT NULL DEFAULT FALSE,
+			members_can_invite BOOLEAN NOT NULL DEFAULT FALSE,
+			messages_ttl INTEGER NOT NULL DEFAULT 0,
+			message_sequence BIGINT NOT NULL DEFAULT 0
+		)`,
+		`CREATE UNIQUE INDEX uniq_channel ON channels (name, creator_id)`,
+		`CREATE UNIQUE INDEX uniq_channel_public_id ON channels (public_id)`,
+
+		`CREATE TABLE channel_memberships (
+			id SERIAL PRIMARY KEY,
+			channel_id INTEGER NOT NULL REFERENCES channels (id) ON DELETE CASCADE ON UPDATE CASCADE,
+			user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE,
+			invited TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			joined TIMESTAMP WITH TIME ZONE DEFAULT NULL
+		)`,
+		`CREATE UNIQUE INDEX uniq_membership ON channel_memberships (channel_id, user_id)`,
+
+		`CREATE TABLE messages (
+			id SERIAL PRIMARY KEY,
+			channel_id INTEGER NOT NULL REFERENCES channels (id) ON DELETE CASCADE ON UPDATE CASCADE,
+			sender_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE,
+			receiver_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE,
+			public_id VARCHAR(22) NOT NULL,
+			sequence BIGINT NOT NULL,
+			client_id VARCHAR(36) DEFAULT NULL,
+			message BYTEA NOT NULL,
+			keys BYTEA NOT NULL,
+			integrity BYTEA NOT NULL,
+			posted TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			delivered TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+			seen TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+			edited TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+			deleted TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+			expires TIMESTAMP WITH TIME ZONE DEFAULT NULL
+		)`,
+		`CREATE INDEX idx_message_public_id ON messages (public_id)`,
+		`CREATE UNIQUE INDEX uniq_message_sequence ON messages (channel_id, receiver_id, sequence)`,
+		`CREATE UNIQUE INDEX uniq_message_client_id ON messages (sender_id, client_id, receiver_id)`,
+
+		`CREATE TABLE attachments (
+			id SERIAL PRIMARY KEY,
+			owner_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE,
+			channel_id INTEGER NOT NULL REFERENCES channels (id) ON DELETE CASCADE ON UPDATE CASCADE,
+			public_id VARCHAR(22) NOT NULL,
+			size BIGINT NOT NULL,
+			received BIGINT NOT NULL DEFAULT 0,
+			created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			completed TIMESTAMP WITH TIME ZONE DEFAULT NULL
+		)`,
+		`CREATE UNIQUE INDEX uniq_attachment_public_id ON attachments (public_id)`,
+		`CREATE INDEX idx_attachment_owner ON attachments (owner_id)`,
+	},
+	Down: []string{
+		`DROP TABLE attachments`,
+		`DROP TABLE messages`,
+		`DROP TABLE channel_memberships`,
+		`DROP TABLE channels`,
+		`DROP TABLE users`,
+	},
+	AdoptTable: "users",
+	Adopt:      adoptSchema,
+}
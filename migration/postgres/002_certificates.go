@@ -0,0 +1,26 @@
+package postgres
+
+import "github.com/krostar/nebulo-server/migration"
+
+// certificates of deleted users are kept to be listed in the revocation lists
+var certificates = migration.Migration{
+	Version:     2,
+	Description: "create issued certificates table",
+	Up: []string{
+		`CREATE TABLE certificates (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			serial VARCHAR(40) NOT NULL,
+			not_before TIMESTAMP WITH TIME ZONE NOT NULL,
+			not_after TIMESTAMP WITH TIME ZONE NOT NULL,
+			status VARCHAR(16) NOT NULL,
+			issued TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			revoked TIMESTAMP WITH TIME ZONE DEFAULT NULL
+		)`,
+		`CREATE UNIQUE INDEX uniq_certificate_serial ON certificates (serial)`,
+		`CREATE INDEX idx_certificate_user ON certificates (user_id)`,
+	},
+	Down: []string{
+		`DROP TABLE certificates`,
+	},
+}
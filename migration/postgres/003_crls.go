@@ -0,0 +1,22 @@
+package postgres
+
+import "github.com/krostar/nebulo-server/migration"
+
+var crls = migration.Migration{
+	Version:     3,
+	Description: "create certificate revocation lists table",
+	Up: []string{
+		`CREATE INDEX idx_certificate_status ON certificates (status, not_after)`,
+
+		`CREATE TABLE crls (
+			id SERIAL PRIMARY KEY,
+			this_update TIMESTAMP WITH TIME ZONE NOT NULL,
+			next_update TIMESTAMP WITH TIME ZONE NOT NULL,
+			der BYTEA NOT NULL
+		)`,
+	},
+	Down: []string{
+		`DROP TABLE crls`,
+		`DROP INDEX idx_certificate_status`,
+	},
+}
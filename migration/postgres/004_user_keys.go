@@ -0,0 +1,25 @@
+package postgres
+
+import "github.com/krostar/nebulo-server/migration"
+
+var userKeys = migration.Migration{
+	Version:     4,
+	Description: "create users key history table",
+	Up: []string{
+		`CREATE TABLE user_keys (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE,
+			key_public_der BYTEA NOT NULL,
+			key_fingerprint VARCHAR(51) NOT NULL,
+			added TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			retired TIMESTAMP WITH TIME ZONE DEFAULT NULL
+		)`,
+		`CREATE UNIQUE INDEX uniq_user_key ON user_keys (key_public_der)`,
+		`CREATE INDEX idx_user_key_user ON user_keys (user_id)`,
+		`INSERT INTO user_keys (user_id, key_public_der, key_fingerprint, added)
+			SELECT id, key_public_der, key_fingerprint, signup FROM users ORDER BY id`,
+	},
+	Down: []string{
+		`DROP TABLE user_keys`,
+	},
+}
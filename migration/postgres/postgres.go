@@ -0,0 +1,11 @@
+package postgres
+
+import "github.com/krostar/nebulo-server/migration"
+
+// Migrations are the migrations of the PostgreSQL databases, ordered by version
+var Migrations = []migration.Migration{
+	initialSchema,
+	certificates,
+	crls,
+	userKeys,
+}
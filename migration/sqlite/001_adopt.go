@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/krostar/nebulo-server/migration"
+)
+
+// adoptSchema bring the tables created by the providers before the migrations existed to the initial schema,
+// the columns, indexes and tables are only added when missing: the schema depends on the release which created them;
+// SQLite can't add a column without default value to a table, the added columns keep their default value
+var adoptSchema = []migration.Step{
+	joinMembers,
+	addColumns("channels",
+		`public_id VARCHAR(22) NOT NULL DEFAULT ''`,
+		`messages_ttl INTEGER NOT NULL DEFAULT 0`,
+		`message_sequence BIGINT NOT NULL DEFAULT 0`,
+	),
+	addColumns("messages",
+		`public_id VARCHAR(22) NOT NULL DEFAULT ''`,
+		`sequence BIGINT NOT NULL DEFAULT 0`,
+		`client_id VARCHAR(36) DEFAULT NULL`,
+		`delivered DATETIME DEFAULT NULL`,
+		`edited DATETIME DEFAULT NULL`,
+		`deleted DATETIME DEFAULT NULL`,
+		`expires DATETIME DEFAULT NULL`,
+	),
+	migration.FillPublicIDs("channels"),
+	migration.NumberMessages(),
+	migration.Exec(
+		// the indexes may be missing, their creation failed with the foreign keys or was not done on SQLite
+		`CREATE UNIQUE INDEX IF NOT EXISTS uniq_user ON users (key_public_der)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS uniq_channel ON channels (name, creator_id)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS uniq_channel_public_id ON channels (public_id)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS uniq_membership ON channel_memberships (channel_id, user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_message_public_id ON messages (public_id)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS uniq_message_sequence ON messages (channel_id, receiver_id, sequence)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS uniq_message_client_id ON messages (sender_id, client_id, receiver_id)`,
+
+		`CREATE TABLE IF NOT EXISTS attachments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			owner_id INTEGER NOT NULL,
+			channel_id INTEGER NOT NULL,
+			public_id VARCHAR(22) NOT NULL,
+			size BIGINT NOT NULL,
+			received BIGINT NOT NULL DEFAULT 0,
+			created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			completed DATETIME DEFAULT NULL
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS uniq_attachment_public_id ON attachments (public_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_attachment_owner ON attachments (owner_id)`,
+	),
+}
+
+// joinMembers mark every member as joined in the databases created before the invitations,
+// the channels had no public identifier then
+func joinMembers(tx *gorm.DB) (err error) {
+	columns, err := listColumns(tx, "channels")
+	if err != nil || columns["public_id"] {
+		return err
+	}
+	if err = tx.Exec(`UPDATE channel_memberships SET joined = invited WHERE joined IS NULL`).Error; err != nil {
+		return fmt.Errorf("unable to mark members as joined: %v", err)
+	}
+	return nil
+}
+
+// addColumns return a step adding the columns missing from the table,
+// a column is defined by its name followed by its type and constraints
+func addColumns(table string, definitions ...string) migration.Step {
+	return func(tx *gorm.DB) (err error) {
+		columns, err := listColumns(tx, table)
+		if err != nil {
+			return err
+		}
+
+		for _, definition := range definitions {
+			if columns[strings.Fields(definition)[0]] {
+				continue
+			}
+			if err = tx.Exec("ALTER TABLE " + table + " ADD COLUMN " + definition).Error; err != nil {
+				return fmt.Errorf("unable to add column to %s: %v", table, err)
+			}
+		}
+		return nil
+	}
+}
+
+// listColumns return the names of the columns of the table
+func listColumns(tx *gorm.DB, table string) (columns map[string]bool, err error) {
+	var infos []struct {
+		Name string
+	}
+	if err = tx.Raw("PRAGMA table_info(" + table + ")").Scan(&infos).Error; err != nil {
+		return nil, fmt.Errorf("unable to list columns of %s: %v", table, err)
+	}
+
+	columns = make(map[string]bool, len(infos))
+	for _, info := range infos {
+		columns[info.Name] = true
+	}
+	return columns, nil
+}
@@ -0,0 +1,88 @@
+package sqlite
+
+import "github.com/krostar/nebulo-server/migration"
+
+// SQLite does not enforce foreign keys by default, they are not declared
+var initialSchema = migration.Migration{
+	Version:     1,
+	Description: "create users, channels, memberships, messages and attachments tables",
+	Up: []string{
+		`CREATE TABLE users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			key_public_der BLOB NOT NULL,
+			key_fingerprint VARCHAR(51) NOT NULL,
+			display_name VARCHAR(42),
+			signup DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			login_first DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00',
+			login_last DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00'
+		)`,
+		`CREATE UNIQUE INDEX uniq_user ON users (key_public_der)`,
+
+		`CREATE TABLE channels (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			creator_id INTEGER NOT NULL,
+			public_id VARCHAR(22) NOT NULL,
+			name VARCHAR(64),
+			created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			members_can_edit BOOLEAN NOT NULL DEFAULT 0,
+			members_can_invite BOOLEAN NOT NULL DEFAULT 0,
+			messages_ttl INTEGER NOT NULL DEFAULT 0,
+			message_sequence BIGINT NOT NULL DEFAULT 0
+		)`,
+		`CREATE UNIQUE INDEX uniq_channel ON channels (name, creator_id)`,
+		`CREATE UNIQUE INDEX uniq_channel_public_id ON channels (public_id)`,
+
+		`CREATE TABLE channel_memberships (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			channel_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			invited DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			joined DATETIME DEFAULT NULL
+		)`,
+		`CREATE UNIQUE INDEX uniq_membership ON channel_memberships (channel_id, user_id)`,
+
+		`CREATE TABLE messages (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			channel_id INTEGER NOT NULL,
+			sender_id INTEGER NOT NULL,
+			receiver_id INTEGER NOT NULL,
+			public_id VARCHAR(22) NOT NULL,
+			sequence BIGINT NOT NULL,
+			client_id VARCHAR(36) DEFAULT NULL,
+			message BLOB NOT NULL,
+			keys BLOB NOT NULL,
+			integrity BLOB NOT NULL,
+			posted DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			delivered DATETIME DEFAULT NULL,
+			seen DATETIME DEFAULT NULL,
+			edited DATETIME DEFAULT NULL,
+			deleted DATETIME DEFAULT NULL,
+			expires DATETIME DEFAULT NULL
+		)`,
+		`CREATE INDEX idx_message_public_id ON messages (public_id)`,
+		`CREATE UNIQUE INDEX uniq_message_sequence ON messages (channel_id, receiver_id, sequence)`,
+		`CREATE UNIQUE INDEX uniq_message_client_id ON messages (sender_id, client_id, receiver_id)`,
+
+		`CREATE TABLE attachments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			owner_id INTEGER NOT NULL,
+			channel_id INTEGER NOT NULL,
+			public_id VARCHAR(22) NOT NULL,
+			size BIGINT NOT NULL,
+			received BIGINT NOT NULL DEFAULT 0,
+			created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			completed DATETIME DEFAULT NULL
+		)`,
+		`CREATE UNIQUE INDEX uniq_attachment_public_id ON attachments (public_id)`,
+		`CREATE INDEX idx_attachment_owner ON attachments (owner_id)`,
+	},
+	Down: []string{
+		`DROP TABLE attachments`,
+		`DROP TABLE messages`,
+		`DROP TABLE channel_memberships`,
+		`DROP TABLE channels`,
+		`DROP TABLE users`,
+	},
+	AdoptTable: "users",
+	Adopt:      adoptSchema,
+}
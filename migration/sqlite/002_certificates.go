@@ -0,0 +1,25 @@
+package sqlite
+
+import "github.com/krostar/nebulo-server/migration"
+
+var certificates = migration.Migration{
+	Version:     2,
+	Description: "create issued certificates table",
+	Up: []string{
+		`CREATE TABLE certificates (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			serial VARCHAR(40) NOT NULL,
+			not_before DATETIME NOT NULL,
+			not_after DATETIME NOT NULL,
+			status VARCHAR(16) NOT NULL,
+			issued DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			revoked DATETIME DEFAULT NULL
+		)`,
+		`CREATE UNIQUE INDEX uniq_certificate_serial ON certificates (serial)`,
+		`CREATE INDEX idx_certificate_user ON certificates (user_id)`,
+	},
+	Down: []string{
+		`DROP TABLE certificates`,
+	},
+}
@@ -0,0 +1,21 @@
+package sqlite
+
+import "github.com/krostar/nebulo-server/migration"
+
+var crls = migration.Migration{
+	Version:     3,
+	Description: "create certificate revocation lists table",
+	Up: []string{
+		`CREATE TABLE crls (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			this_update DATETIME NOT NULL,
+			next_update DATETIME NOT NULL,
+			der BLOB NOT NULL
+		)`,
+		`CREATE INDEX idx_certificate_status ON certificates (status, not_after)`,
+	},
+	Down: []string{
+		`DROP INDEX idx_certificate_status`,
+		`DROP TABLE crls`,
+	},
+}
@@ -0,0 +1,280 @@
+package conformance
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	gp "github.com/krostar/nebulo-golib/provider"
+	gpSQLite "github.com/krostar/nebulo-golib/provider/sqlite"
+
+	apSQLite "github.com/krostar/nebulo-server/attachment/provider/sqlite"
+	certpSQLite "github.com/krostar/nebulo-server/certificate/provider/sqlite"
+	cpSQLite "github.com/krostar/nebulo-server/channel/provider/sqlite"
+	mpSQLite "github.com/krostar/nebulo-server/message/provider/sqlite"
+	"github.com/krostar/nebulo-server/migration"
+	mgSQLite "github.com/krostar/nebulo-server/migration/sqlite"
+	"github.com/krostar/nebulo-server/user"
+	upSQLite "github.com/krostar/nebulo-server/user/provider/sqlite"
+)
+
+// the tables as they were created by the providers before the migrations existed
+type (
+	legacyUser struct {
+		ID           int       `gorm:"column:id; primary_key; not null"`
+		PublicKeyDER []byte    `gorm:"column:key_public_der; size:2000; not null"`
+		FingerPrint  string    `gorm:"column:key_fingerprint; size:51; not null"`
+		DisplayName  string    `gorm:"column:display_name; size:42"`
+		Signup       time.Time `gorm:"column:signup; not null" sql:"DEFAULT:current_timestamp"`
+		LoginFirst   time.Time `gorm:"column:login_first; not null" sql:"DEFAULT:'1970-01-01 00:00:00'"`
+		LoginLast    time.Time `gorm:"column:login_last; not null" sql:"DEFAULT:'1970-01-01 00:00:00'"`
+	}
+	legacyChannel struct {
+		ID               int       `gorm:"column:id; not null"`
+		CreatorID        int       `gorm:"column:creator_id; not null"`
+		Name             string    `gorm:"column:name; size:64"`
+		Created          time.Time `gorm:"column:created; not null" sql:"DEFAULT:current_timestamp"`
+		MembersCanEdit   bool      `gorm:"column:members_can_edit; not null" sql:"DEFAULT:false"`
+		MembersCanInvite bool      `gorm:"column:members_can_invite; not null" sql:"DEFAULT:false"`
+	}
+	legacyMembership struct {
+		ID        int       `gorm:"column:id; not null"`
+		ChannelID int       `gorm:"column:channel_id; not null"`
+		UserID    int       `gorm:"column:user_id; not null"`
+		Invited   time.Time `gorm:"column:invited; not null" sql:"DEFAULT:current_timestamp"`
+		Joined    time.Time `gorm:"column:joined" sql:"DEFAULT:NULL"`
+	}
+	legacyMessage struct {
+		ID         int       `gorm:"column:id; not null"`
+		ChannelID  int       `gorm:"column:channel_id; not null"`
+		SenderID   int       `gorm:"column:sender_id; not null"`
+		ReceiverID int       `gorm:"column:receiver_id; not null"`
+		Message    []byte    `gorm:"column:message; type:blob; not null"`
+		Keys       []byte    `gorm:"column:keys; size:256; not null"`
+		Integrity  []byte    `gorm:"column:integrity; size:32; not null"`
+		Posted     time.Time `gorm:"column:posted; not null" sql:"DEFAULT:current_timestamp"`
+		Seen       time.Time `gorm:"column:seen" sql:"DEFAULT:NULL"`
+	}
+)
+
+func (legacyUser) TableName() string       { return "users" }
+func (legacyChannel) TableName() string    { return "channels" }
+func (legacyMembership) TableName() string { return "channel_memberships" }
+func (legacyMessage) TableName() string    { return "messages" }
+
+// openLegacy open an empty SQLite database, the returned function removes it
+func openLegacy(t *testing.T) (db *gorm.DB, remove func()) {
+	dir, err := ioutil.TempDir("", "nebulo-adoption")
+	if err != nil {
+		t.Fatalf("unable to create temporary directory: %v", err)
+	}
+	if err = gpSQLite.Use(&gp.SQLiteConfig{File: filepath.Join(dir, "legacy.db")}); err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("unable to open database: %v", err)
+	}
+	return gp.RP.DB, func() { os.RemoveAll(dir) }
+}
+
+// adopt apply every migrations to a legacy database and check its schema is the latest one
+func adopt(t *testing.T, db *gorm.DB) {
+	m := migration.New(db, mgSQLite.Migrations)
+	if applied, err := m.Up(-1); err != nil || len(applied) != len(mgSQLite.Migrations) {
+		t.Fatalf("unable to adopt the legacy database, applied %d migrations: %v", len(applied), err)
+	}
+	if err := m.Check(); err != nil {
+		t.Fatalf("adopted database schema is not the latest one: %v", err)
+	}
+}
+
+func TestSQLiteAdoption(t *testing.T) {
+	db, remove := openLegacy(t)
+	defer remove()
+
+	if err := db.CreateTable(&legacyUser{}, &legacyChannel{}, &legacyMembership{}, &legacyMessage{}).Error; err != nil {
+		t.Fatalf("unable to create legacy tables: %v", err)
+	}
+	if err := db.Model(&legacyChannel{}).AddUniqueIndex("uniq_channel", "name", "creator_id").Error; err != nil {
+		t.Fatalf("unable to create legacy indexes: %v", err)
+	}
+	for _, statement := range []string{
+		`INSERT INTO users (id, key_public_der, key_fingerprint) VALUES (1, x'01', 'alice'), (2, x'02', 'bob')`,
+		`INSERT INTO channels (id, creator_id, name) VALUES (1, 1, 'general'), (2, 2, 'random')`,
+		`INSERT INTO channel_memberships (channel_id, user_id) VALUES (1, 1), (1, 2), (2, 2)`,
+		`INSERT INTO messages (channel_id, sender_id, receiver_id, message, keys, integrity) VALUES
+			(1, 1, 2, 'hello', 'k', 'i'), (2, 2, 2, 'note', 'k', 'i'), (1, 2, 1, 'hi', 'k', 'i'), (1, 1, 2, 'again', 'k', 'i')`,
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatalf("unable to insert legacy rows: %v", err)
+		}
+	}
+
+	adopt(t, db)
+
+	p, err := initProviders(nil, upSQLite.Init, cpSQLite.Init, mpSQLite.Init, certpSQLite.Init, apSQLite.Init)
+	if err != nil {
+		t.Fatalf("unable to create providers: %v", err)
+	}
+	alice, err := p.Users.FindByID(1)
+	if err != nil {
+		t.Fatalf("unable to find legacy user: %v", err)
+	}
+	bob, err := p.Users.FindByID(2)
+	if err != nil {
+		t.Fatalf("unable to find legacy user: %v", err)
+	}
+	if keys, err := p.Users.ListKeys(*alice); err != nil || len(keys) != 1 {
+		t.Errorf("legacy user should have its key in the key history, got %d keys: %v", len(keys), err)
+	}
+
+	list, err := p.Channels.List(*bob, 0, 10)
+	if err != nil {
+		t.Fatalf("unable to list channels: %v", err)
+	}
+	if len(list) != 2 || len(list[0].Members) != 2 || len(list[1].Members) != 1 {
+		t.Fatalf("legacy members should keep their channels, got %d channels", len(list))
+	}
+	if list[0].PublicID == "" || list[0].PublicID == list[1].PublicID {
+		t.Errorf("legacy channels should have distinct public ids, got %q and %q", list[0].PublicID, list[1].PublicID)
+	}
+	c, err := p.Channels.FindByPublicID(*alice, list[0].PublicID)
+	if err != nil {
+		t.Fatalf("unable to find legacy channel by its public id: %v", err)
+	}
+
+	received := listMessages(t, p, bob, c)
+	if !equal(contents(received), "hello", "again") || received[0].PublicID == "" || received[0].PublicID == received[1].PublicID {
+		t.Fatalf("legacy messages should be listed with distinct public ids, got %v", contents(received))
+	}
+	if received[0].Sequence != 1 || received[1].Sequence != 3 {
+		t.Errorf("legacy messages should be numbered in their channel, got %d and %d", received[0].Sequence, received[1].Sequence)
+	}
+	postMessage(t, p, alice, c, "world")
+	if received = listMessages(t, p, bob, c); !equal(contents(received), "hello", "again", "world") || received[2].Sequence != 4 {
+		t.Errorf("messages should be numbered after the legacy ones, got %v", contents(received))
+	}
+}
+
+// a legacy message was stored once per receiver, the copies are adopted as a single message
+func TestSQLiteAdoptionWithReceivers(t *testing.T) {
+	db, remove := openLegacy(t)
+	defer remove()
+
+	if err := db.CreateTable(&legacyUser{}, &legacyChannel{}, &legacyMembership{}, &legacyMessage{}).Error; err != nil {
+		t.Fatalf("unable to create legacy tables: %v", err)
+	}
+	for _, statement := range []string{
+		`INSERT INTO users (id, key_public_der, key_fingerprint) VALUES (1, x'01', 'alice'), (2, x'02', 'bob'), (3, x'03', 'carol')`,
+		`INSERT INTO channels (id, creator_id, name) VALUES (1, 1, 'general')`,
+		`INSERT INTO channel_memberships (channel_id, user_id) VALUES (1, 1), (1, 2), (1, 3)`,
+		// the second message of alice is posted during the same second than the first one
+		`INSERT INTO messages (channel_id, sender_id, receiver_id, message, keys, integrity, posted) VALUES
+			(1, 1, 2, 'hello', 'k', 'i', '2017-06-01 10:00:00'), (1, 1, 3, 'hello', 'k', 'i', '2017-06-01 10:00:00'),
+			(1, 2, 1, 'hi', 'k', 'i', '2017-06-01 10:00:00'), (1, 2, 3, 'hi', 'k', 'i', '2017-06-01 10:00:00'),
+			(1, 1, 2, 'again', 'k', 'i', '2017-06-01 10:00:00'), (1, 1, 3, 'again', 'k', 'i', '2017-06-01 10:00:00')`,
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatalf("unable to insert legacy rows: %v", err)
+		}
+	}
+
+	adopt(t, db)
+
+	p, err := initProviders(nil, upSQLite.Init, cpSQLite.Init, mpSQLite.Init, certpSQLite.Init, apSQLite.Init)
+	if err != nil {
+		t.Fatalf("unable to create providers: %v", err)
+	}
+	var users [3]*user.User
+	for i := range users {
+		if users[i], err = p.Users.FindByID(i + 1); err != nil {
+			t.Fatalf("unable to find legacy user: %v", err)
+		}
+	}
+	alice, bob, carol := users[0], users[1], users[2]
+	list, err := p.Channels.List(*alice, 0, 10)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("unable to list legacy channels, got %d channels: %v", len(list), err)
+	}
+	c, err := p.Channels.FindByPublicID(*alice, list[0].PublicID)
+	if err != nil {
+		t.Fatalf("unable to find legacy channel by its public id: %v", err)
+	}
+
+	toBob, toCarol := listMessages(t, p, bob, c), listMessages(t, p, carol, c)
+	if !equal(contents(toBob), "hello", "again") || !equal(contents(toCarol), "hello", "hi", "again") {
+		t.Fatalf("legacy messages should be listed to their receivers, got %v and %v", contents(toBob), contents(toCarol))
+	}
+	for i, j := range []int{0, 2} {
+		if toBob[i].PublicID != toCarol[j].PublicID || toBob[i].Sequence != toCarol[j].Sequence {
+			t.Errorf("copies of %q should share their public id and sequence, got %q/%d and %q/%d", contents(toBob)[i],
+				toBob[i].PublicID, toBob[i].Sequence, toCarol[j].PublicID, toCarol[j].Sequence)
+		}
+	}
+	if toCarol[0].PublicID == toCarol[2].PublicID {
+		t.Errorf("legacy messages posted the same second should have distinct public ids")
+	}
+	for i, m := range toCarol {
+		if m.Sequence != int64(i+1) {
+			t.Errorf("legacy message %q should have sequence %d, got %d", m.Message, i+1, m.Sequence)
+		}
+	}
+
+	postMessage(t, p, alice, c, "world")
+	if toCarol = listMessages(t, p, carol, c); len(toCarol) != 4 || toCarol[3].Sequence != 4 {
+		t.Errorf("messages should be numbered after the legacy ones, got %v", contents(toCarol))
+	}
+}
+
+// the attachments and the invitations existed before the migrations, the attachments had no index on SQLite
+func TestSQLiteAdoptionWithAttachments(t *testing.T) {
+	db, remove := openLegacy(t)
+	defer remove()
+
+	for _, statement := range []string{
+		`CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, key_public_der BLOB NOT NULL, key_fingerprint VARCHAR(51) NOT NULL,
+			display_name VARCHAR(42), signup DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			login_first DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00', login_last DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00')`,
+		`CREATE TABLE channels (id INTEGER PRIMARY KEY AUTOINCREMENT, creator_id INTEGER NOT NULL, public_id VARCHAR(22) NOT NULL,
+			name VARCHAR(64), created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, members_can_edit BOOLEAN NOT NULL DEFAULT 0,
+			members_can_invite BOOLEAN NOT NULL DEFAULT 0, messages_ttl INTEGER NOT NULL DEFAULT 0, message_sequence BIGINT NOT NULL DEFAULT 0)`,
+		`CREATE TABLE channel_memberships (id INTEGER PRIMARY KEY AUTOINCREMENT, channel_id INTEGER NOT NULL, user_id INTEGER NOT NULL,
+			invited DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, joined DATETIME DEFAULT NULL)`,
+		`CREATE TABLE messages (id INTEGER PRIMARY KEY AUTOINCREMENT, channel_id INTEGER NOT NULL, sender_id INTEGER NOT NULL,
+			receiver_id INTEGER NOT NULL, public_id VARCHAR(22) NOT NULL, sequence BIGINT NOT NULL, client_id VARCHAR(36) DEFAULT NULL,
+			message BLOB NOT NULL, keys BLOB NOT NULL, integrity BLOB NOT NULL, posted DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			delivered DATETIME DEFAULT NULL, seen DATETIME DEFAULT NULL, edited DATETIME DEFAULT NULL, deleted DATETIME DEFAULT NULL,
+			expires DATETIME DEFAULT NULL)`,
+		`CREATE TABLE attachments (id INTEGER PRIMARY KEY AUTOINCREMENT, owner_id INTEGER NOT NULL, channel_id INTEGER NOT NULL,
+			public_id VARCHAR(22) NOT NULL, size BIGINT NOT NULL, received BIGINT NOT NULL DEFAULT 0,
+			created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, completed DATETIME DEFAULT NULL)`,
+
+		`INSERT INTO users (id, key_public_der, key_fingerprint) VALUES (1, x'01', 'alice'), (2, x'02', 'bob')`,
+		`INSERT INTO channels (id, creator_id, public_id, name) VALUES (1, 1, 'c1', 'general')`,
+		`INSERT INTO channel_memberships (channel_id, user_id, joined) VALUES (1, 1, CURRENT_TIMESTAMP)`,
+		`INSERT INTO channel_memberships (channel_id, user_id) VALUES (1, 2)`,
+		`INSERT INTO attachments (owner_id, channel_id, public_id, size) VALUES (1, 1, 'a1', 42)`,
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatalf("unable to create legacy database: %v", err)
+		}
+	}
+
+	adopt(t, db)
+
+	p, err := initProviders(nil, upSQLite.Init, cpSQLite.Init, mpSQLite.Init, certpSQLite.Init, apSQLite.Init)
+	if err != nil {
+		t.Fatalf("unable to create providers: %v", err)
+	}
+	bob, err := p.Users.FindByID(2)
+	if err != nil {
+		t.Fatalf("unable to find legacy user: %v", err)
+	}
+	if list, err := p.Channels.List(*bob, 0, 10); err != nil || len(list) != 0 {
+		t.Errorf("legacy invitations should stay pending, got %d channels: %v", len(list), err)
+	}
+	if err = db.Exec(`INSERT INTO attachments (owner_id, channel_id, public_id, size) VALUES (1, 1, 'a1', 42)`).Error; err == nil {
+		t.Errorf("legacy attachments public ids should be unique")
+	}
+}
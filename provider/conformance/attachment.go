@@ -0,0 +1,209 @@
+package conformance
+
+import (
+	"testing"
+
+	"github.com/krostar/nebulo-server/attachment"
+	"github.com/krostar/nebulo-server/channel"
+	"github.com/krostar/nebulo-server/identifier"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// quota is large enough to never be reached, except by the quota tests
+const quota = 1 << 20
+
+// newAttachment register an attachment of the owner in the channel with a new public identifier
+func newAttachment(t *testing.T, p *Providers, owner *user.User, c *channel.Channel, size int64) *attachment.Attachment {
+	publicID, err := identifier.New()
+	if err != nil {
+		t.Fatalf("unable to generate public id: %v", err)
+	}
+	a, err := p.Attachments.Create(*owner, *c, publicID, size, quota)
+	if err != nil {
+		t.Fatalf("unable to create attachment: %v", err)
+	}
+	if a.ID == 0 || a.PublicID != publicID || a.Size != size || a.Received != 0 || a.IsComplete() {
+		t.Fatalf("created attachment is not valid: %+v", a)
+	}
+	return a
+}
+
+// listAttachments return the public identifiers of the attachments of the channel
+func listAttachments(t *testing.T, p *Providers, c *channel.Channel) (ids []string) {
+	list, err := p.Attachments.ListByChannel(*c)
+	if err != nil {
+		t.Fatalf("unable to list attachments: %v", err)
+	}
+	for _, a := range list {
+		ids = append(ids, a.PublicID)
+	}
+	return ids
+}
+
+func testAttachments(t *testing.T, p *Providers) {
+	owner, _ := newUser(t, p)
+	member, _ := newUser(t, p)
+	c := newChannel(t, p, "chan", owner, member)
+	other := newChannel(t, p, "other", owner, member)
+
+	first := newAttachment(t, p, owner, c, 42)
+	second := newAttachment(t, p, member, c, 12)
+	newAttachment(t, p, owner, other, 8)
+
+	found, err := p.Attachments.FindByPublicID(*c, first.PublicID)
+	if err != nil {
+		t.Fatalf("unable to find attachment: %v", err)
+	}
+	if found.ID != first.ID || found.Owner.ID != owner.ID || found.Channel.ID != c.ID || found.Size != 42 {
+		t.Errorf("found attachment %+v does not match the created one %+v", found, first)
+	}
+	if _, err = p.Attachments.FindByPublicID(*other, first.PublicID); err != attachment.ErrNotFound {
+		t.Errorf("finding an attachment in an other channel should fail with %v, got %v", attachment.ErrNotFound, err)
+	}
+	if _, err = p.Attachments.FindByPublicID(*c, "unknown"); err != attachment.ErrNotFound {
+		t.Errorf("finding an unknown attachment should fail with %v, got %v", attachment.ErrNotFound, err)
+	}
+
+	if ids := listAttachments(t, p, c); !equal(ids, first.PublicID, second.PublicID) {
+		t.Errorf("listed attachments %v, expected the 2 attachments of the channel", ids)
+	}
+	list, err := p.Attachments.ListByUser(*member)
+	if err != nil {
+		t.Fatalf("unable to list attachments: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != second.ID {
+		t.Errorf("listed %d attachments, expected the attachment of the member", len(list))
+	}
+	// the attachments of the channels created by the user are removed with him
+	if list, err = p.Attachments.ListByUser(*owner); err != nil || len(list) != 3 {
+		t.Errorf("listed %d attachments, expected the 3 attachments of the channels of the creator: %v", len(list), err)
+	}
+}
+
+func testAttachmentsQuota(t *testing.T, p *Providers) {
+	owner, _ := newUser(t, p)
+	member, _ := newUser(t, p)
+	c := newChannel(t, p, "chan", owner, member)
+
+	if used, err := p.Attachments.UsedSpace(*owner); err != nil || used != 0 {
+		t.Errorf("used space without attachments should be 0, got %d: %v", used, err)
+	}
+	complete := newAttachment(t, p, owner, c, 6)
+	if err := p.Attachments.Received(complete, 0, 6); err != nil {
+		t.Fatalf("unable to update received size: %v", err)
+	}
+	newAttachment(t, p, owner, c, 3)
+	newAttachment(t, p, member, c, 5)
+
+	// complete or not, every attachments count
+	if used, err := p.Attachments.UsedSpace(*owner); err != nil || used != 9 {
+		t.Errorf("used space should be 9, got %d: %v", used, err)
+	}
+
+	if _, err := p.Attachments.Create(*owner, *c, "exceeding", 2, 10); err != attachment.ErrQuotaExceeded {
+		t.Errorf("exceeding the quota should fail with %v, got %v", attachment.ErrQuotaExceeded, err)
+	}
+	if ids := listAttachments(t, p, c); len(ids) != 3 {
+		t.Errorf("an attachment exceeding the quota should not be created, %d attachments listed", len(ids))
+	}
+	if _, err := p.Attachments.Create(*owner, *c, "filling", 1, 10); err != nil {
+		t.Errorf("filling the quota should succeed, got %v", err)
+	}
+	if used, err := p.Attachments.UsedSpace(*owner); err != nil || used != 10 {
+		t.Errorf("used space should be 10, got %d: %v", used, err)
+	}
+	if used, err := p.Attachments.UsedSpace(*member); err != nil || used != 5 {
+		t.Errorf("used space of an other user should be 5, got %d: %v", used, err)
+	}
+}
+
+func testAttachmentsUpload(t *testing.T, p *Providers) {
+	owner, _ := newUser(t, p)
+	c := newChannel(t, p, "chan", owner)
+	a := newAttachment(t, p, owner, c, 10)
+
+	if err := p.Attachments.Received(nil, 0, 4); err != attachment.ErrNil {
+		t.Errorf("updating a nil attachment should fail with %v, got %v", attachment.ErrNil, err)
+	}
+	if err := p.Attachments.Received(a, 0, 4); err != nil {
+		t.Fatalf("unable to update received size: %v", err)
+	}
+	if a.Received != 4 || a.IsComplete() {
+		t.Errorf("attachment should have received 4 bytes and not be complete, got %+v", a)
+	}
+
+	// a chunk written from a stale offset claims an already written part
+	stale := *a
+	stale.Received = 0
+	if err := p.Attachments.Received(&stale, 0, 6); err != attachment.ErrOffsetMismatch {
+		t.Errorf("updating from a stale offset should fail with %v, got %v", attachment.ErrOffsetMismatch, err)
+	}
+	if found, err := p.Attachments.FindByPublicID(*c, a.PublicID); err != nil || found.Received != 4 {
+		t.Errorf("stored received size should stay 4, got %+v: %v", found, err)
+	}
+
+	if err := p.Attachments.Received(a, 4, 10); err != nil {
+		t.Fatalf("unable to update received size: %v", err)
+	}
+	if a.Received != 10 || !a.IsComplete() {
+		t.Errorf("attachment should be complete, got %+v", a)
+	}
+	found, err := p.Attachments.FindByPublicID(*c, a.PublicID)
+	if err != nil {
+		t.Fatalf("unable to find attachment: %v", err)
+	}
+	if found.Received != 10 || !found.IsComplete() {
+		t.Errorf("stored attachment should be complete, got %+v", found)
+	}
+}
+
+func testAttachmentsDeletion(t *testing.T, p *Providers) {
+	owner, _ := newUser(t, p)
+	member, _ := newUser(t, p)
+	c := newChannel(t, p, "chan", owner, member)
+	kept := newChannel(t, p, "kept", member, owner)
+
+	deleted := newAttachment(t, p, owner, c, 1)
+	remaining := newAttachment(t, p, member, c, 1)
+	if err := p.Attachments.Delete(nil); err != attachment.ErrNil {
+		t.Errorf("deleting a nil attachment should fail with %v, got %v", attachment.ErrNil, err)
+	}
+	if err := p.Attachments.Delete(deleted); err != nil {
+		t.Fatalf("unable to delete attachment: %v", err)
+	}
+	if ids := listAttachments(t, p, c); !equal(ids, remaining.PublicID) {
+		t.Errorf("listed attachments %v, expected only the remaining attachment", ids)
+	}
+
+	newAttachment(t, p, owner, kept, 1)
+	if err := p.Attachments.DeleteByChannel(*c); err != nil {
+		t.Fatalf("unable to delete channel attachments: %v", err)
+	}
+	if ids := listAttachments(t, p, c); len(ids) != 0 {
+		t.Errorf("channel still has %d attachments", len(ids))
+	}
+	if ids := listAttachments(t, p, kept); len(ids) != 1 {
+		t.Errorf("deletion removed attachments of an other channel, %d left", len(ids))
+	}
+
+	// the attachments are deleted with their channel
+	newAttachment(t, p, member, c, 1)
+	if err := p.Channels.Delete(c); err != nil {
+		t.Fatalf("unable to delete channel: %v", err)
+	}
+	if ids := listAttachments(t, p, c); len(ids) != 0 {
+		t.Errorf("deleted channel still has %d attachments", len(ids))
+	}
+
+	// and with their owner, even in the channels of an other user
+	newAttachment(t, p, member, kept, 1)
+	if err := p.Users.Delete(owner); err != nil {
+		t.Fatalf("unable to delete user: %v", err)
+	}
+	if ids := listAttachments(t, p, kept); len(ids) != 1 {
+		t.Errorf("deleted user attachments should be deleted, %d attachments left", len(ids))
+	}
+	if used, err := p.Attachments.UsedSpace(*owner); err != nil || used != 0 {
+		t.Errorf("deleted user should not use space, got %d: %v", used, err)
+	}
+}
@@ -0,0 +1,147 @@
+package conformance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/krostar/nebulo-server/certificate"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// newCertificate register a certificate issued to the owner with a new serial number
+func newCertificate(t *testing.T, p *Providers, owner *user.User, notBefore time.Time) *certificate.Certificate {
+	serial, err := certificate.NewSerial()
+	if err != nil {
+		t.Fatalf("unable to generate serial: %v", err)
+	}
+	c, err := p.Certificates.Create(*owner, serial, notBefore, notBefore.Add(7*24*time.Hour))
+	if err != nil {
+		t.Fatalf("unable to create certificate: %v", err)
+	}
+	if c.ID == 0 || c.Serial != certificate.FormatSerial(serial) || c.Status != certificate.StatusValid {
+		t.Fatalf("created certificate is not valid: %+v", c)
+	}
+	return c
+}
+
+func testCertificates(t *testing.T, p *Providers) {
+	u, _ := newUser(t, p)
+	other, _ := newUser(t, p)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	first := newCertificate(t, p, u, now.Add(-time.Hour))
+	second := newCertificate(t, p, u, now)
+	newCertificate(t, p, other, now)
+
+	serial, err := certificate.ParseSerial(second.Serial)
+	if err != nil {
+		t.Fatalf("unable to parse serial: %v", err)
+	}
+	found, err := p.Certificates.FindBySerial(serial)
+	if err != nil {
+		t.Fatalf("unable to find certificate: %v", err)
+	}
+	if found.ID != second.ID || found.User.ID != u.ID || !found.NotBefore.Equal(now) || !found.NotAfter.Equal(now.Add(7*24*time.Hour)) {
+		t.Errorf("found certificate %+v does not match the created one %+v", found, second)
+	}
+
+	if status, err := p.Certificates.FindStatus(serial); err != nil || status != certificate.StatusValid {
+		t.Errorf("certificate status should be %q, got %q: %v", certificate.StatusValid, status, err)
+	}
+
+	unknown, _ := certificate.NewSerial()
+	if _, err = p.Certificates.FindBySerial(unknown); err != certificate.ErrNotFound {
+		t.Errorf("finding an unknown serial should fail with %v, got %v", certificate.ErrNotFound, err)
+	}
+	if _, err = p.Certificates.FindStatus(unknown); err != certificate.ErrNotFound {
+		t.Errorf("finding the status of an unknown serial should fail with %v, got %v", certificate.ErrNotFound, err)
+	}
+
+	list, err := p.Certificates.ListByUser(*u)
+	if err != nil {
+		t.Fatalf("unable to list certificates: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != first.ID || list[1].ID != second.ID {
+		t.Errorf("listed %d certificates, expected the 2 certificates of the user in issuance order", len(list))
+	}
+}
+
+func testCertificatesRevocation(t *testing.T, p *Providers) {
+	u, _ := newUser(t, p)
+	c := newCertificate(t, p, u, time.Now())
+
+	if err := p.Certificates.Revoke(nil, time.Now()); err != certificate.ErrNil {
+		t.Errorf("revoking a nil certificate should fail with %v, got %v", certificate.ErrNil, err)
+	}
+	if err := p.Certificates.Revoke(&certificate.Certificate{ID: c.ID + 42}, time.Now()); err != certificate.ErrNotFound {
+		t.Errorf("revoking an unknown certificate should fail with %v, got %v", certificate.ErrNotFound, err)
+	}
+
+	revoked := time.Now().UTC().Truncate(time.Second)
+	if err := p.Certificates.Revoke(c, revoked); err != nil {
+		t.Fatalf("unable to revoke certificate: %v", err)
+	}
+	if !c.IsRevoked() || c.Revoked == nil || !c.Revoked.Equal(revoked) {
+		t.Errorf("revoked certificate is not updated: %+v", c)
+	}
+	if err := p.Certificates.Revoke(c, time.Now()); err != certificate.ErrAlreadyRevoked {
+		t.Errorf("revoking twice should fail with %v, got %v", certificate.ErrAlreadyRevoked, err)
+	}
+
+	serial, _ := certificate.ParseSerial(c.Serial)
+	found, err := p.Certificates.FindBySerial(serial)
+	if err != nil {
+		t.Fatalf("unable to find certificate: %v", err)
+	}
+	if !found.IsRevoked() || found.Revoked == nil || !found.Revoked.Equal(revoked) {
+		t.Errorf("found certificate is not revoked: %+v", found)
+	}
+	if status, err := p.Certificates.FindStatus(serial); err != nil || status != certificate.StatusRevoked {
+		t.Errorf("certificate status should be %q, got %q: %v", certificate.StatusRevoked, status, err)
+	}
+}
+
+func testCertificatesRevokedList(t *testing.T, p *Providers) {
+	u, _ := newUser(t, p)
+	now := time.Now().UTC()
+
+	expired := newCertificate(t, p, u, now.Add(-8*24*time.Hour))
+	first := newCertificate(t, p, u, now)
+	newCertificate(t, p, u, now)
+	second := newCertificate(t, p, u, now)
+	for i, c := range []*certificate.Certificate{expired, second, first} {
+		if err := p.Certificates.Revoke(c, now.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("unable to revoke certificate: %v", err)
+		}
+	}
+
+	list, err := p.Certificates.ListRevoked(now)
+	if err != nil {
+		t.Fatalf("unable to list revoked certificates: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != second.ID || list[1].ID != first.ID {
+		t.Errorf("listed %d revoked certificates, expected the 2 not expired ones in revocation order", len(list))
+	}
+}
+
+func testCertificatesCRL(t *testing.T, p *Providers) {
+	if _, err := p.Certificates.LastCRL(); err != certificate.ErrCRLNotFound {
+		t.Errorf("getting a crl before any generation should fail with %v, got %v", certificate.ErrCRLNotFound, err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i, der := range []string{"first", "second"} {
+		crl := &certificate.CRL{ThisUpdate: now.Add(time.Duration(i) * time.Hour), NextUpdate: now.Add(time.Duration(i+2) * time.Hour), DER: []byte(der)}
+		if err := p.Certificates.SaveCRL(crl); err != nil {
+			t.Fatalf("unable to save crl: %v", err)
+		}
+	}
+
+	crl, err := p.Certificates.LastCRL()
+	if err != nil {
+		t.Fatalf("unable to get crl: %v", err)
+	}
+	if string(crl.DER) != "second" || !crl.ThisUpdate.Equal(now.Add(time.Hour)) || !crl.NextUpdate.Equal(now.Add(3*time.Hour)) {
+		t.Errorf("last crl is not the last saved one: %+v", crl)
+	}
+}
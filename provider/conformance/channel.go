@@ -0,0 +1,281 @@
+package conformance
+
+import (
+	"testing"
+
+	"github.com/krostar/nebulo-server/channel"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// newChannel create a channel whose members joined
+func newChannel(t *testing.T, p *Providers, name string, creator *user.User, members ...*user.User) *channel.Channel {
+	users := []user.User{*creator}
+	for _, m := range members {
+		users = append(users, *m)
+	}
+
+	c, err := p.Channels.Create(name, *creator, users)
+	if err != nil {
+		t.Fatalf("unable to create channel %q: %v", name, err)
+	}
+	if c.ID == 0 || c.PublicID == "" {
+		t.Fatalf("created channel %q has no identifiers", name)
+	}
+	return c
+}
+
+func testChannels(t *testing.T, p *Providers) {
+	creator, _ := newUser(t, p)
+	member, _ := newUser(t, p)
+	stranger, _ := newUser(t, p)
+	c := newChannel(t, p, "chan", creator, member)
+
+	found, err := p.Channels.FindByPublicID(*member, c.PublicID)
+	if err != nil {
+		t.Fatalf("unable to find channel: %v", err)
+	}
+	if found.ID != c.ID || found.Name != "chan" || found.Creator.ID != creator.ID || len(found.Members) != 2 {
+		t.Errorf("found channel does not match the created one: %+v", found)
+	}
+	if _, err = p.Channels.FindByPublicID(*stranger, c.PublicID); err != channel.ErrNotFound {
+		t.Errorf("a channel should not be found by a non member, got %v", err)
+	}
+	if _, err = p.Channels.FindByPublicID(*member, "unknown"); err != channel.ErrNotFound {
+		t.Errorf("finding an unknown channel should fail with %v, got %v", channel.ErrNotFound, err)
+	}
+
+	if found, err = p.Channels.FindByID(c.ID); err != nil || found.PublicID != c.PublicID {
+		t.Errorf("unable to find channel by id: %v, %v", found, err)
+	}
+	if found, err = p.Channels.Find(channel.Channel{PublicID: c.PublicID}); err != nil || found.ID != c.ID {
+		t.Errorf("unable to find channel by public id: %v, %v", found, err)
+	}
+	if _, err = p.Channels.FindByID(c.ID + 1000); err != channel.ErrNotFound {
+		t.Errorf("finding an unknown channel should fail with %v, got %v", channel.ErrNotFound, err)
+	}
+
+	if err = p.Channels.Update(nil, map[string]interface{}{"name": "nil"}); err != channel.ErrNil {
+		t.Errorf("updating a nil channel should fail with %v, got %v", channel.ErrNil, err)
+	}
+	if err = p.Channels.Update(found, map[string]interface{}{
+		"name":               "renamed",
+		"members_can_invite": true,
+		"messages_ttl":       60,
+	}); err != nil {
+		t.Fatalf("unable to update channel: %v", err)
+	}
+	if found.Name != "renamed" || !found.MembersCanInvite || found.MessagesTTL != 60 {
+		t.Errorf("updated channel is not updated: %+v", found)
+	}
+	if found, err = p.Channels.FindByPublicID(*creator, c.PublicID); err != nil {
+		t.Fatalf("unable to find channel: %v", err)
+	}
+	if found.Name != "renamed" || !found.MembersCanInvite || found.MessagesTTL != 60 || found.MembersCanEdit {
+		t.Errorf("stored channel is not updated: %+v", found)
+	}
+}
+
+func testChannelsCreation(t *testing.T, p *Providers) {
+	creator, _ := newUser(t, p)
+	other, _ := newUser(t, p)
+
+	c := newChannel(t, p, "chan", creator)
+	again := newChannel(t, p, "chan", creator)
+	if again.ID != c.ID || again.PublicID != c.PublicID {
+		t.Errorf("creating the same channel twice created a new channel: %d and %d", c.ID, again.ID)
+	}
+
+	if named := newChannel(t, p, "other", creator); named.ID == c.ID || named.PublicID == c.PublicID {
+		t.Error("a channel with an other name should be a new channel")
+	}
+	if owned := newChannel(t, p, "chan", other); owned.ID == c.ID || owned.PublicID == c.PublicID {
+		t.Error("a channel with an other creator should be a new channel")
+	}
+}
+
+func testChannelsList(t *testing.T, p *Providers) {
+	u, _ := newUser(t, p)
+	other, _ := newUser(t, p)
+
+	names := []string{"a", "b", "c", "d", "e"}
+	for _, name := range names {
+		newChannel(t, p, name, u)
+	}
+	newChannel(t, p, "not mine", other)
+	invited := newChannel(t, p, "invited", other)
+	um, err := p.Channels.Invite(invited, *u)
+	if err != nil {
+		t.Fatalf("unable to invite: %v", err)
+	}
+
+	all, err := p.Channels.List(*u, 0, 100)
+	if err != nil {
+		t.Fatalf("unable to list channels: %v", err)
+	}
+	if len(all) != len(names) {
+		t.Fatalf("listed %d channels, expected %d", len(all), len(names))
+	}
+	for i, c := range all {
+		if c.Name != names[i] {
+			t.Errorf("listed channel %d is %q, expected %q", i, c.Name, names[i])
+		}
+	}
+
+	for offset := 0; offset < len(names); offset += 2 {
+		page, err := p.Channels.List(*u, offset, 2)
+		if err != nil {
+			t.Fatalf("unable to list channels at offset %d: %v", offset, err)
+		}
+		expected := 2
+		if left := len(names) - offset; left < expected {
+			expected = left
+		}
+		if len(page) != expected {
+			t.Errorf("listed %d channels at offset %d, expected %d", len(page), offset, expected)
+			continue
+		}
+		for i, c := range page {
+			if c.PublicID != all[offset+i].PublicID {
+				t.Errorf("channel %q listed at offset %d, expected %q", c.Name, offset+i, all[offset+i].Name)
+			}
+			if len(c.Members) != 1 || c.Creator.ID != u.ID {
+				t.Errorf("listed channel %q members and creator are not loaded", c.Name)
+			}
+		}
+	}
+
+	// members are ordered by ID and creators are loaded even when they are not the listing user
+	if err = p.Channels.Join(um); err != nil {
+		t.Fatalf("unable to join: %v", err)
+	}
+	all, err = p.Channels.List(*u, 0, 100)
+	if err != nil {
+		t.Fatalf("unable to list channels: %v", err)
+	}
+	if last := all[len(all)-1]; last.PublicID != invited.PublicID {
+		t.Errorf("joined channel should be listed last, got %q", last.Name)
+	} else if last.Creator.ID != other.ID || len(last.Members) != 2 ||
+		last.Members[0].ID != u.ID || last.Members[1].ID != other.ID {
+		t.Errorf("joined channel members and creator are not loaded: %+v", last)
+	}
+	names = append(names, invited.Name)
+
+	if page, err := p.Channels.List(*u, len(names), 2); err != nil || len(page) != 0 {
+		t.Errorf("listing after the last channel should be empty: %v, %v", page, err)
+	}
+}
+
+func testChannelsMemberships(t *testing.T, p *Providers) {
+	creator, _ := newUser(t, p)
+	invitee, _ := newUser(t, p)
+	c := newChannel(t, p, "chan", creator)
+
+	if _, err := p.Channels.Invite(nil, *invitee); err != channel.ErrNil {
+		t.Errorf("inviting in a nil channel should fail with %v, got %v", channel.ErrNil, err)
+	}
+	if _, err := p.Channels.Invite(c, *creator); err != channel.ErrAlreadyMember {
+		t.Errorf("inviting a member should fail with %v, got %v", channel.ErrAlreadyMember, err)
+	}
+	um, err := p.Channels.Invite(c, *invitee)
+	if err != nil {
+		t.Fatalf("unable to invite: %v", err)
+	}
+	if !um.IsPending() || um.User.ID != invitee.ID || um.Channel.ID != c.ID {
+		t.Errorf("invitation is not a pending membership of the invitee: %+v", um)
+	}
+	if _, err = p.Channels.Invite(c, *invitee); err != channel.ErrAlreadyMember {
+		t.Errorf("inviting twice should fail with %v, got %v", channel.ErrAlreadyMember, err)
+	}
+
+	invitations, err := p.Channels.ListInvitations(*invitee)
+	if err != nil {
+		t.Fatalf("unable to list invitations: %v", err)
+	}
+	if len(invitations) != 1 || invitations[0].Channel.PublicID != c.PublicID {
+		t.Errorf("invitations does not contain the channel: %+v", invitations)
+	}
+	if invitations, err = p.Channels.ListInvitations(*creator); err != nil || len(invitations) != 0 {
+		t.Errorf("a member should not have invitations: %+v, %v", invitations, err)
+	}
+
+	// a pending invitee is not a member yet
+	if _, err = p.Channels.FindByPublicID(*invitee, c.PublicID); err != channel.ErrNotFound {
+		t.Errorf("an invitee should not find the channel before joining, got %v", err)
+	}
+	if um, err = p.Channels.FindMembership(*invitee, c.PublicID); err != nil || !um.IsPending() {
+		t.Fatalf("unable to find pending membership: %+v, %v", um, err)
+	}
+
+	if err = p.Channels.Join(nil); err != channel.ErrMembershipNotFound {
+		t.Errorf("joining a nil membership should fail with %v, got %v", channel.ErrMembershipNotFound, err)
+	}
+	if err = p.Channels.Join(um); err != nil {
+		t.Fatalf("unable to join: %v", err)
+	}
+	if um.IsPending() {
+		t.Error("joined membership is still pending")
+	}
+	found, err := p.Channels.FindByPublicID(*invitee, c.PublicID)
+	if err != nil {
+		t.Fatalf("a member should find the channel: %v", err)
+	}
+	if len(found.Members) != 2 {
+		t.Errorf("channel has %d members, expected 2", len(found.Members))
+	}
+	if invitations, err = p.Channels.ListInvitations(*invitee); err != nil || len(invitations) != 0 {
+		t.Errorf("a joined membership is not an invitation: %+v, %v", invitations, err)
+	}
+
+	if err = p.Channels.Leave(nil); err != channel.ErrMembershipNotFound {
+		t.Errorf("leaving a nil membership should fail with %v, got %v", channel.ErrMembershipNotFound, err)
+	}
+	if um, err = p.Channels.FindMembership(*invitee, c.PublicID); err != nil || um.IsPending() {
+		t.Fatalf("unable to find membership: %+v, %v", um, err)
+	}
+	if err = p.Channels.Leave(um); err != nil {
+		t.Fatalf("unable to leave: %v", err)
+	}
+	if _, err = p.Channels.FindMembership(*invitee, c.PublicID); err != channel.ErrMembershipNotFound {
+		t.Errorf("a left membership should fail with %v, got %v", channel.ErrMembershipNotFound, err)
+	}
+	if _, err = p.Channels.FindByPublicID(*invitee, c.PublicID); err != channel.ErrNotFound {
+		t.Errorf("a channel should not be found after leaving, got %v", err)
+	}
+}
+
+func testChannelsDeletion(t *testing.T, p *Providers) {
+	creator, _ := newUser(t, p)
+	member, _ := newUser(t, p)
+	c := newChannel(t, p, "chan", creator, member)
+	kept := newChannel(t, p, "kept", creator, member)
+	postMessage(t, p, creator, c, "deleted")
+	postMessage(t, p, creator, kept, "kept")
+
+	if err := p.Channels.Delete(nil); err != channel.ErrNil {
+		t.Errorf("deleting a nil channel should fail with %v, got %v", channel.ErrNil, err)
+	}
+	if err := p.Channels.Delete(&channel.Channel{}); err != channel.ErrNotFound {
+		t.Errorf("deleting a channel without id should fail with %v, got %v", channel.ErrNotFound, err)
+	}
+	if err := p.Channels.Delete(c); err != nil {
+		t.Fatalf("unable to delete channel: %v", err)
+	}
+
+	if _, err := p.Channels.FindByID(c.ID); err != channel.ErrNotFound {
+		t.Errorf("deleted channel should not be found, got %v", err)
+	}
+	// memberships and messages are deleted with the channel
+	if _, err := p.Channels.FindMembership(*member, c.PublicID); err != channel.ErrMembershipNotFound {
+		t.Errorf("deleted channel memberships should not be found, got %v", err)
+	}
+	if m := listMessages(t, p, member, c); len(m) != 0 {
+		t.Errorf("deleted channel still has %d messages", len(m))
+	}
+
+	if _, err := p.Channels.FindByPublicID(*member, kept.PublicID); err != nil {
+		t.Errorf("deletion removed an other channel: %v", err)
+	}
+	if m := listMessages(t, p, member, kept); len(m) != 1 {
+		t.Errorf("deletion removed messages of an other channel, %d left", len(m))
+	}
+}
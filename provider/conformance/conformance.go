@@ -0,0 +1,68 @@
+package conformance
+
+import (
+	"testing"
+
+	ap "github.com/krostar/nebulo-server/attachment/provider"
+	certp "github.com/krostar/nebulo-server/certificate/provider"
+	cp "github.com/krostar/nebulo-server/channel/provider"
+	mp "github.com/krostar/nebulo-server/message/provider"
+	up "github.com/krostar/nebulo-server/user/provider"
+)
+
+// Providers are the providers of a backend, they must share the same storage
+type Providers struct {
+	Users        up.Provider
+	Channels     cp.Provider
+	Messages     mp.Provider
+	Certificates certp.Provider
+	Attachments  ap.Provider
+}
+
+// Constructor return the providers of a backend on an empty storage, with the tables created
+type Constructor func() (*Providers, error)
+
+// Run check that the providers returned by the constructor behave like every other backend,
+// each test get new providers
+func Run(t *testing.T, newProviders Constructor) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, p *Providers)
+	}{
+		{"users", testUsers},
+		{"users login", testUsersLogin},
+		{"users keys", testUsersKeys},
+		{"channels", testChannels},
+		{"channels creation", testChannelsCreation},
+		{"channels list", testChannelsList},
+		{"channels memberships", testChannelsMemberships},
+		{"channels deletion", testChannelsDeletion},
+		{"messages creation", testMessagesCreation},
+		{"messages list", testMessagesList},
+		{"messages edition", testMessagesEdition},
+		{"messages deletion", testMessagesDeletion},
+		{"messages range deletion", testMessagesRangeDeletion},
+		{"messages expiration", testMessagesExpiration},
+		{"messages receipts", testMessagesReceipts},
+		{"messages acknowledgement", testMessagesAcknowledgement},
+		{"certificates", testCertificates},
+		{"certificates revocation", testCertificatesRevocation},
+		{"certificates revoked list", testCertificatesRevokedList},
+		{"certificates crl", testCertificatesCRL},
+		{"attachments", testAttachments},
+		{"attachments quota", testAttachmentsQuota},
+		{"attachments upload", testAttachmentsUpload},
+		{"attachments deletion", testAttachmentsDeletion},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newProviders()
+			if err != nil {
+				t.Fatalf("unable to create providers: %v", err)
+			}
+			tt.test(t, p)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package conformance
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	gp "github.com/krostar/nebulo-golib/provider"
+	gpSQLite "github.com/krostar/nebulo-golib/provider/sqlite"
+	_ "github.com/lib/pq" // postgres driver used by gorm
+
+	ap "github.com/krostar/nebulo-server/attachment/provider"
+	apMemory "github.com/krostar/nebulo-server/attachment/provider/memory"
+	apPostgres "github.com/krostar/nebulo-server/attachment/provider/postgres"
+	apSQLite "github.com/krostar/nebulo-server/attachment/provider/sqlite"
+	certp "github.com/krostar/nebulo-server/certificate/provider"
+	certpMemory "github.com/krostar/nebulo-server/certificate/provider/memory"
+	certpPostgres "github.com/krostar/nebulo-server/certificate/provider/postgres"
+	certpSQLite "github.com/krostar/nebulo-server/certificate/provider/sqlite"
+	cp "github.com/krostar/nebulo-server/channel/provider"
+	cpMemory "github.com/krostar/nebulo-server/channel/provider/memory"
+	cpPostgres "github.com/krostar/nebulo-server/channel/provider/postgres"
+	cpSQLite "github.com/krostar/nebulo-server/channel/provider/sqlite"
+	mp "github.com/krostar/nebulo-server/message/provider"
+	mpMemory "github.com/krostar/nebulo-server/message/provider/memory"
+	mpPostgres "github.com/krostar/nebulo-server/message/provider/postgres"
+	mpSQLite "github.com/krostar/nebulo-server/message/provider/sqlite"
+	"github.com/krostar/nebulo-server/migration"
+	mgPostgres "github.com/krostar/nebulo-server/migration/postgres"
+	mgSQLite "github.com/krostar/nebulo-server/migration/sqlite"
+	gpMemory "github.com/krostar/nebulo-server/provider/memory"
+	up "github.com/krostar/nebulo-server/user/provider"
+	upMemory "github.com/krostar/nebulo-server/user/provider/memory"
+	upPostgres "github.com/krostar/nebulo-server/user/provider/postgres"
+	upSQLite "github.com/krostar/nebulo-server/user/provider/sqlite"
+)
+
+func TestSQLite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nebulo-conformance")
+	if err != nil {
+		t.Fatalf("unable to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	databases := 0
+	Run(t, func() (*Providers, error) {
+		// every test get its own database file
+		databases++
+		if err := gpSQLite.Use(&gp.SQLiteConfig{File: filepath.Join(dir, fmt.Sprintf("%d.db", databases))}); err != nil {
+			return nil, err
+		}
+		return initProviders(mgSQLite.Migrations, upSQLite.Init, cpSQLite.Init, mpSQLite.Init, certpSQLite.Init, apSQLite.Init)
+	})
+}
+
+// TestPostgres run the suite against the PostgreSQL database of the $NEBULO_TEST_POSTGRES connection string,
+// like postgres://nebulo@127.0.0.1:5433/nebulo?sslmode=disable for the server of 'make postgres-start';
+// the database is emptied by the test
+func TestPostgres(t *testing.T) {
+	dsn := os.Getenv("NEBULO_TEST_POSTGRES")
+	if dsn == "" {
+		t.Skip("NEBULO_TEST_POSTGRES is not set")
+	}
+	db, err := gorm.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("unable to open database: %v", err)
+	}
+	defer db.Close()
+	gp.RP = &gp.RootProvider{DB: db}
+
+	m := migration.New(db, mgPostgres.Migrations)
+	Run(t, func() (*Providers, error) {
+		// every test starts from an empty schema, reverting the migrations of the previous one
+		if _, err := m.Down(0); err != nil {
+			return nil, err
+		}
+		return initProviders(mgPostgres.Migrations, upPostgres.Init, cpPostgres.Init, mpPostgres.Init, certpPostgres.Init, apPostgres.Init)
+	})
+
+	if _, err = m.Down(0); err != nil {
+		t.Fatalf("unable to revert migrations: %v", err)
+	}
+	var tables []string
+	if err = db.Raw(`SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema()`).
+		Pluck("table_name", &tables).Error; err != nil {
+		t.Fatalf("unable to list tables: %v", err)
+	}
+	if len(tables) != 1 || tables[0] != "schema_version" {
+		t.Errorf("reverted migrations should only keep the schema version table, got %v", tables)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	Run(t, func() (*Providers, error) {
+		if err := gpMemory.Use(); err != nil {
+			return nil, err
+		}
+		return initProviders(nil, upMemory.Init, cpMemory.Init, mpMemory.Init, certpMemory.Init, apMemory.Init)
+	})
+}
+
+// initProviders initialize the providers of a backend and migrate its schema if it has one
+func initProviders(migrations []migration.Migration, inits ...func() error) (p *Providers, err error) {
+	for _, init := range inits {
+		if err = init(); err != nil {
+			return nil, err
+		}
+	}
+
+	if migrations != nil {
+		if _, err = migration.New(gp.RP.DB, migrations).Up(-1); err != nil {
+			return nil, err
+		}
+	}
+	return &Providers{Users: up.P, Channels: cp.P, Messages: mp.P, Certificates: certp.P, Attachments: ap.P}, nil
+}
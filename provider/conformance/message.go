@@ -0,0 +1,414 @@
+package conformance
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/krostar/nebulo-server/channel"
+	"github.com/krostar/nebulo-server/identifier"
+	"github.com/krostar/nebulo-server/message"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// secureMsgs return a content for each member who joined the channel
+func secureMsgs(t *testing.T, p *Providers, sender *user.User, c *channel.Channel, content string) map[int]message.SecureMsg {
+	found, err := p.Channels.FindByPublicID(*sender, c.PublicID)
+	if err != nil {
+		t.Fatalf("unable to find channel: %v", err)
+	}
+
+	msgs := make(map[int]message.SecureMsg)
+	for _, m := range found.Members {
+		msgs[m.ID] = message.SecureMsg{
+			Message:   []byte(content),
+			Keys:      []byte("keys"),
+			Integrity: []byte("integrity"),
+		}
+	}
+	return msgs
+}
+
+// postMessage create a message for every members of the channel and return its public identifier
+func postMessage(t *testing.T, p *Providers, sender *user.User, c *channel.Channel, content string) string {
+	publicID, err := identifier.New()
+	if err != nil {
+		t.Fatalf("unable to generate public id: %v", err)
+	}
+	if _, err = p.Messages.Create(*sender, *c, publicID, "", 0, secureMsgs(t, p, sender, c, content)); err != nil {
+		t.Fatalf("unable to create message: %v", err)
+	}
+	return publicID
+}
+
+// listMessages return every receiver copies of the channel messages
+func listMessages(t *testing.T, p *Providers, receiver *user.User, c *channel.Channel) []*message.Message {
+	m, _, err := p.Messages.List(*receiver, *c, message.Page{Limit: 1000})
+	if err != nil {
+		t.Fatalf("unable to list messages: %v", err)
+	}
+	return m
+}
+
+// contents return the content of the messages
+func contents(m []*message.Message) (c []string) {
+	for _, mm := range m {
+		c = append(c, string(mm.Message))
+	}
+	return c
+}
+
+func equal(a []string, b ...string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func testMessagesCreation(t *testing.T, p *Providers) {
+	sender, _ := newUser(t, p)
+	receiver, _ := newUser(t, p)
+	stranger, _ := newUser(t, p)
+	c := newChannel(t, p, "chan", sender, receiver)
+
+	msgs := secureMsgs(t, p, sender, c, "hello")
+	m, err := p.Messages.Create(*sender, *c, "public", "client", 0, msgs)
+	if err != nil {
+		t.Fatalf("unable to create message: %v", err)
+	}
+	if len(m) != 2 {
+		t.Fatalf("created %d copies, expected 2", len(m))
+	}
+	for _, mm := range m {
+		if mm.PublicID != "public" || mm.Sequence != 1 || mm.SenderID != sender.ID || mm.Expires != nil {
+			t.Errorf("created copy does not match: %+v", mm)
+		}
+	}
+
+	found, err := p.Messages.FindByClientID(*sender, "client")
+	if err != nil || len(found) != 2 || found[0].PublicID != "public" {
+		t.Errorf("unable to find message by client id: %+v, %v", found, err)
+	}
+	if _, err = p.Messages.FindByClientID(*receiver, "client"); err != message.ErrNotFound {
+		t.Errorf("client ids are unique per sender only, got %v", err)
+	}
+	if _, err = p.Messages.Create(*sender, *c, "again", "client", 0, msgs); err != message.ErrClientIDExists {
+		t.Errorf("reusing a client id should fail with %v, got %v", message.ErrClientIDExists, err)
+	}
+
+	missing := map[int]message.SecureMsg{sender.ID: msgs[sender.ID]}
+	if _, err = p.Messages.Create(*sender, *c, "missing", "", 0, missing); err != message.ErrReceiversMismatch {
+		t.Errorf("missing a receiver should fail with %v, got %v", message.ErrReceiversMismatch, err)
+	}
+	extra := secureMsgs(t, p, sender, c, "extra")
+	extra[stranger.ID] = msgs[sender.ID]
+	if _, err = p.Messages.Create(*sender, *c, "extra", "", 0, extra); err != message.ErrReceiversMismatch {
+		t.Errorf("an extra receiver should fail with %v, got %v", message.ErrReceiversMismatch, err)
+	}
+
+	// failed creations do not consume a sequence number
+	if m, err = p.Messages.Create(*sender, *c, "second", "", 0, msgs); err != nil {
+		t.Fatalf("unable to create message: %v", err)
+	}
+	if m[0].Sequence != 2 {
+		t.Errorf("second message sequence is %d, expected 2", m[0].Sequence)
+	}
+	if l := listMessages(t, p, receiver, c); len(l) != 2 {
+		t.Errorf("receiver has %d messages, expected 2", len(l))
+	}
+}
+
+func testMessagesList(t *testing.T, p *Providers) {
+	sender, _ := newUser(t, p)
+	receiver, _ := newUser(t, p)
+	c := newChannel(t, p, "chan", sender, receiver)
+	for _, content := range []string{"1", "2", "3", "4", "5"} {
+		postMessage(t, p, sender, c, content)
+	}
+
+	tests := []struct {
+		page     message.Page
+		expected []string
+		hasMore  bool
+	}{
+		{message.Page{Limit: 0}, nil, false},
+		{message.Page{Limit: 2}, []string{"4", "5"}, true},
+		{message.Page{Limit: 10}, []string{"1", "2", "3", "4", "5"}, false},
+		{message.Page{Limit: 2, Before: 4}, []string{"2", "3"}, true},
+		{message.Page{Limit: 2, Before: 3}, []string{"1", "2"}, false},
+		{message.Page{Limit: 2, After: 1}, []string{"2", "3"}, true},
+		{message.Page{Limit: 2, After: 3}, []string{"4", "5"}, false},
+		{message.Page{Limit: 10, After: 1, Before: 5}, []string{"2", "3", "4"}, false},
+		{message.Page{Limit: 2, After: 5}, nil, false},
+	}
+	for _, tt := range tests {
+		m, hasMore, err := p.Messages.List(*receiver, *c, tt.page)
+		if err != nil {
+			t.Fatalf("unable to list messages of page %+v: %v", tt.page, err)
+		}
+		if !equal(contents(m), tt.expected...) || hasMore != tt.hasMore {
+			t.Errorf("page %+v listed %v (more: %t), expected %v (more: %t)", tt.page, contents(m), hasMore, tt.expected, tt.hasMore)
+		}
+		for _, mm := range m {
+			if mm.ReceiverID != receiver.ID || mm.Receiver.ID != receiver.ID || mm.Sender.ID != sender.ID || mm.Channel.ID != c.ID {
+				t.Errorf("listed message %d does not belong to the receiver or misses its associations", mm.ID)
+			}
+			if mm.Delivered == nil {
+				t.Errorf("listed message %d is not marked as delivered", mm.ID)
+			}
+		}
+	}
+
+	// the sender has its own copies and an other channel does not share them
+	if m := listMessages(t, p, sender, c); len(m) != 5 || m[0].ReceiverID != sender.ID {
+		t.Errorf("sender has %d copies, expected 5", len(m))
+	}
+	other := newChannel(t, p, "other", sender, receiver)
+	if m := listMessages(t, p, receiver, other); len(m) != 0 {
+		t.Errorf("an other channel has %d messages, expected none", len(m))
+	}
+
+	// every messages get their own sender
+	postMessage(t, p, receiver, other, "6")
+	postMessage(t, p, sender, other, "7")
+	m := listMessages(t, p, receiver, other)
+	if len(m) != 2 || m[0].Sender.ID != receiver.ID || m[1].Sender.ID != sender.ID {
+		t.Errorf("listed messages senders are not loaded: %v", m)
+	}
+
+	// the channel is embedded with its members and creator
+	found, err := p.Channels.FindByPublicID(*receiver, other.PublicID)
+	if err != nil {
+		t.Fatalf("unable to find channel: %v", err)
+	}
+	for _, mm := range listMessages(t, p, receiver, found) {
+		if len(mm.Channel.Members) != 2 || mm.Channel.Creator.ID != sender.ID {
+			t.Errorf("listed message %d channel misses its members or creator", mm.ID)
+		}
+	}
+}
+
+func testMessagesEdition(t *testing.T, p *Providers) {
+	sender, _ := newUser(t, p)
+	receiver, _ := newUser(t, p)
+	c := newChannel(t, p, "chan", sender, receiver)
+	publicID := postMessage(t, p, sender, c, "before")
+
+	if _, err := p.Messages.Edit(*receiver, *c, publicID, secureMsgs(t, p, sender, c, "hijack")); err != message.ErrNotFound {
+		t.Errorf("only the sender can edit a message, got %v", err)
+	}
+	partial := map[int]message.SecureMsg{receiver.ID: {Message: []byte("partial")}}
+	if _, err := p.Messages.Edit(*sender, *c, publicID, partial); err != message.ErrReceiversMismatch {
+		t.Errorf("editing only some copies should fail with %v, got %v", message.ErrReceiversMismatch, err)
+	}
+
+	m, err := p.Messages.Edit(*sender, *c, publicID, secureMsgs(t, p, sender, c, "after"))
+	if err != nil {
+		t.Fatalf("unable to edit message: %v", err)
+	}
+	if len(m) != 2 || m[0].Edited == nil || string(m[0].Message) != "after" {
+		t.Errorf("edited copies are not updated: %+v", m)
+	}
+	if l := listMessages(t, p, receiver, c); len(l) != 1 || string(l[0].Message) != "after" || l[0].Edited == nil {
+		t.Errorf("stored copy is not edited: %v", contents(l))
+	}
+}
+
+func testMessagesDeletion(t *testing.T, p *Providers) {
+	sender, _ := newUser(t, p)
+	receiver, _ := newUser(t, p)
+	c := newChannel(t, p, "chan", sender, receiver)
+	soft := postMessage(t, p, sender, c, "soft")
+	hard := postMessage(t, p, sender, c, "hard")
+
+	if _, err := p.Messages.Delete(*receiver, *c, soft, false); err != message.ErrNotFound {
+		t.Errorf("only the sender can delete a message, got %v", err)
+	}
+
+	m, err := p.Messages.Delete(*sender, *c, soft, false)
+	if err != nil {
+		t.Fatalf("unable to soft delete message: %v", err)
+	}
+	if len(m) != 2 || m[0].Deleted == nil || len(m[0].Message) != 0 {
+		t.Errorf("soft deleted copies are not wiped: %+v", m)
+	}
+	l := listMessages(t, p, receiver, c)
+	if len(l) != 2 || l[0].Deleted == nil || len(l[0].Message) != 0 || len(l[0].Keys) != 0 {
+		t.Errorf("soft deleted copy should be kept wiped: %+v", l)
+	}
+	if _, err = p.Messages.Delete(*sender, *c, soft, false); err != message.ErrNotFound {
+		t.Errorf("soft deleting twice should fail with %v, got %v", message.ErrNotFound, err)
+	}
+	if _, err = p.Messages.Edit(*sender, *c, soft, secureMsgs(t, p, sender, c, "edit")); err != message.ErrNotFound {
+		t.Errorf("a soft deleted message can not be edited, got %v", err)
+	}
+
+	if _, err = p.Messages.Delete(*sender, *c, hard, true); err != nil {
+		t.Fatalf("unable to hard delete message: %v", err)
+	}
+	// a soft deleted message can still be hard deleted
+	if _, err = p.Messages.Delete(*sender, *c, soft, true); err != nil {
+		t.Fatalf("unable to hard delete a soft deleted message: %v", err)
+	}
+	if l = listMessages(t, p, receiver, c); len(l) != 0 {
+		t.Errorf("hard deleted messages are still listed: %v", contents(l))
+	}
+	if _, err = p.Messages.Delete(*sender, *c, hard, true); err != message.ErrNotFound {
+		t.Errorf("hard deleting twice should fail with %v, got %v", message.ErrNotFound, err)
+	}
+}
+
+func testMessagesRangeDeletion(t *testing.T, p *Providers) {
+	sender, _ := newUser(t, p)
+	receiver, _ := newUser(t, p)
+	c := newChannel(t, p, "chan", sender, receiver)
+	var ids []string
+	for _, content := range []string{"1", "2", "3", "4", "5"} {
+		ids = append(ids, postMessage(t, p, sender, c, content))
+	}
+
+	deleted, err := p.Messages.DeleteRange(*receiver, *c, message.Range{FromID: ids[1], ToID: ids[2]})
+	if err != nil {
+		t.Fatalf("unable to delete range: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("deleted %d messages, expected 2", deleted)
+	}
+	if l := contents(listMessages(t, p, receiver, c)); !equal(l, "1", "4", "5") {
+		t.Errorf("receiver messages are %v after range deletion", l)
+	}
+	// only the receiver copies are deleted
+	if l := contents(listMessages(t, p, sender, c)); !equal(l, "1", "2", "3", "4", "5") {
+		t.Errorf("sender messages are %v after receiver range deletion", l)
+	}
+
+	if deleted, err = p.Messages.DeleteRange(*receiver, *c, message.Range{FromID: "unknown"}); err != nil || deleted != 0 {
+		t.Errorf("a range from an unknown message should delete nothing: %d, %v", deleted, err)
+	}
+	if deleted, err = p.Messages.DeleteRange(*receiver, *c, message.Range{From: time.Now().Add(time.Hour)}); err != nil || deleted != 0 {
+		t.Errorf("a range in the future should delete nothing: %d, %v", deleted, err)
+	}
+	if deleted, err = p.Messages.DeleteRange(*receiver, *c, message.Range{ToID: ids[3]}); err != nil || deleted != 2 {
+		t.Errorf("a range up to the fourth message should delete 2 messages: %d, %v", deleted, err)
+	}
+	if deleted, err = p.Messages.DeleteRange(*receiver, *c, message.Range{To: time.Now().Add(time.Hour)}); err != nil || deleted != 1 {
+		t.Errorf("a range up to now should delete the last message: %d, %v", deleted, err)
+	}
+}
+
+func testMessagesExpiration(t *testing.T, p *Providers) {
+	sender, _ := newUser(t, p)
+	receiver, _ := newUser(t, p)
+	c := newChannel(t, p, "chan", sender, receiver)
+	postMessage(t, p, sender, c, "forever")
+
+	m, err := p.Messages.Create(*sender, *c, "ephemeral", "", time.Hour, secureMsgs(t, p, sender, c, "ephemeral"))
+	if err != nil {
+		t.Fatalf("unable to create message: %v", err)
+	}
+	if m[0].Expires == nil {
+		t.Fatal("message with a ttl has no expiration date")
+	}
+
+	if deleted, err := p.Messages.DeleteExpired(time.Now()); err != nil || deleted != 0 {
+		t.Errorf("no message expired yet: %d, %v", deleted, err)
+	}
+	deleted, err := p.Messages.DeleteExpired(time.Now().Add(2 * time.Hour))
+	if err != nil {
+		t.Fatalf("unable to delete expired messages: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("deleted %d expired copies, expected 2", deleted)
+	}
+	if l := contents(listMessages(t, p, receiver, c)); !equal(l, "forever") {
+		t.Errorf("receiver messages are %v after expiration", l)
+	}
+}
+
+func testMessagesReceipts(t *testing.T, p *Providers) {
+	sender, _ := newUser(t, p)
+	receiver, _ := newUser(t, p)
+	c := newChannel(t, p, "chan", sender, receiver)
+	first := postMessage(t, p, sender, c, "1")
+	second := postMessage(t, p, sender, c, "2")
+	third := postMessage(t, p, sender, c, "3")
+
+	r, err := p.Messages.Receipts(*sender, *c, first)
+	if err != nil {
+		t.Fatalf("unable to get receipts: %v", err)
+	}
+	if len(r) != 2 || r[0].Receiver > r[1].Receiver {
+		t.Errorf("receipts are not ordered by receiver fingerprint: %+v", r)
+	}
+	for _, rr := range r {
+		if rr.Message != first || rr.Delivered != nil || rr.Seen != nil {
+			t.Errorf("receipt of a new message does not match: %+v", rr)
+		}
+	}
+	if _, err = p.Messages.Receipts(*receiver, *c, first); err != message.ErrNotFound {
+		t.Errorf("only the sender can get the receipts, got %v", err)
+	}
+
+	m, err := p.Messages.MarkSeen(*receiver, *c, []string{second})
+	if err != nil {
+		t.Fatalf("unable to mark message as seen: %v", err)
+	}
+	if len(m) != 1 || m[0].Seen == nil || m[0].Delivered == nil {
+		t.Errorf("seen message is not marked: %+v", m)
+	}
+	if m, err = p.Messages.MarkSeen(*receiver, *c, []string{second}); err != nil || len(m) != 0 {
+		t.Errorf("an already seen message should not be returned: %+v, %v", m, err)
+	}
+
+	if _, err = p.Messages.MarkSeenUpTo(*receiver, *c, "unknown"); err != message.ErrNotFound {
+		t.Errorf("marking up to an unknown message should fail with %v, got %v", message.ErrNotFound, err)
+	}
+	if m, err = p.Messages.MarkSeenUpTo(*receiver, *c, third); err != nil || len(m) != 2 {
+		t.Errorf("marking up to the third message should return the first and third: %+v, %v", m, err)
+	}
+
+	for _, publicID := range []string{first, second, third} {
+		if r, err = p.Messages.Receipts(*sender, *c, publicID); err != nil {
+			t.Fatalf("unable to get receipts: %v", err)
+		}
+		for _, rr := range r {
+			if rr.Receiver == receiver.FingerPrint && (rr.Seen == nil || rr.Delivered == nil) {
+				t.Errorf("receipt of message %q is not seen", publicID)
+			}
+			if rr.Receiver == sender.FingerPrint && rr.Seen != nil {
+				t.Errorf("sender copy of message %q should not be seen", publicID)
+			}
+		}
+	}
+}
+
+func testMessagesAcknowledgement(t *testing.T, p *Providers) {
+	sender, _ := newUser(t, p)
+	receiver, _ := newUser(t, p)
+	c := newChannel(t, p, "chan", sender, receiver)
+	first := postMessage(t, p, sender, c, "1")
+	postMessage(t, p, sender, c, "2")
+
+	if m, err := p.Messages.Acknowledge(*receiver, *c, nil); err != nil || len(m) != 0 {
+		t.Errorf("acknowledging nothing should not fail: %+v, %v", m, err)
+	}
+	m, err := p.Messages.Acknowledge(*receiver, *c, []string{first, "unknown"})
+	if err != nil {
+		t.Fatalf("unable to acknowledge messages: %v", err)
+	}
+	if len(m) != 1 || m[0].PublicID != first || !bytes.Equal(m[0].Message, []byte("1")) {
+		t.Errorf("acknowledged copies do not match: %+v", m)
+	}
+
+	if l := contents(listMessages(t, p, receiver, c)); !equal(l, "2") {
+		t.Errorf("receiver messages are %v after acknowledgement", l)
+	}
+	if l := contents(listMessages(t, p, sender, c)); !equal(l, "1", "2") {
+		t.Errorf("sender messages are %v after receiver acknowledgement", l)
+	}
+}
@@ -0,0 +1,188 @@
+package conformance
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/krostar/nebulo-server/user"
+)
+
+// newUser create an user with a new public key
+func newUser(t *testing.T, p *Providers) (u *user.User, key *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("unable to marshal public key: %v", err)
+	}
+
+	if u, err = p.Users.Create(&user.User{PublicKeyDER: der, FingerPrint: base64.RawURLEncoding.EncodeToString(der[len(der)-32:])}); err != nil {
+		t.Fatalf("unable to create user: %v", err)
+	}
+	if u.ID == 0 {
+		t.Fatal("created user has no id")
+	}
+	return u, key
+}
+
+func testUsers(t *testing.T, p *Providers) {
+	u, key := newUser(t, p)
+	other, _ := newUser(t, p)
+
+	if _, err := p.Users.Create(nil); err != user.ErrNil {
+		t.Errorf("creating a nil user should fail with %v, got %v", user.ErrNil, err)
+	}
+	if _, err := p.Users.Create(&user.User{PublicKeyDER: u.PublicKeyDER}); err == nil {
+		t.Error("creating an user with an existing public key should fail")
+	}
+
+	finders := map[string]func() (*user.User, error){
+		"id":             func() (*user.User, error) { return p.Users.FindByID(u.ID) },
+		"public key":     func() (*user.User, error) { return p.Users.FindByPublicKey(&key.PublicKey) },
+		"public key der": func() (*user.User, error) { return p.Users.FindByPublicKeyDER(u.PublicKeyDER) },
+		"public key der base64": func() (*user.User, error) {
+			return p.Users.FindByPublicKeyDERBase64(base64.StdEncoding.EncodeToString(u.PublicKeyDER))
+		},
+	}
+	for by, find := range finders {
+		found, err := find()
+		if err != nil {
+			t.Errorf("unable to find user by %s: %v", by, err)
+		} else if found.ID != u.ID || found.FingerPrint != u.FingerPrint {
+			t.Errorf("user found by %s is %d, expected %d", by, found.ID, u.ID)
+		}
+	}
+	if _, err := p.Users.FindByID(other.ID + 1000); err != user.ErrNotFound {
+		t.Errorf("finding an unknown user should fail with %v, got %v", user.ErrNotFound, err)
+	}
+	if _, err := p.Users.FindByPublicKeyDER([]byte("unknown")); err != user.ErrNotFound {
+		t.Errorf("finding an unknown public key should fail with %v, got %v", user.ErrNotFound, err)
+	}
+
+	if err := p.Users.Update(u, map[string]interface{}{"display_name": "alice"}); err != nil {
+		t.Fatalf("unable to update user: %v", err)
+	}
+	if u.DisplayName != "alice" {
+		t.Errorf("updated user display name is %q, expected %q", u.DisplayName, "alice")
+	}
+	if found, err := p.Users.FindByID(u.ID); err != nil || found.DisplayName != "alice" {
+		t.Errorf("stored user display name is not updated: %v, %v", found, err)
+	}
+	if found, err := p.Users.FindByID(other.ID); err != nil || found.DisplayName != "" {
+		t.Errorf("update changed an other user: %v, %v", found, err)
+	}
+
+	if err := p.Users.Delete(nil); err != user.ErrNil {
+		t.Errorf("deleting a nil user should fail with %v, got %v", user.ErrNil, err)
+	}
+	if err := p.Users.Delete(u); err != nil {
+		t.Fatalf("unable to delete user: %v", err)
+	}
+	if _, err := p.Users.FindByID(u.ID); err != user.ErrNotFound {
+		t.Errorf("deleted user should not be found, got %v", err)
+	}
+	if err := p.Users.Delete(u); err != user.ErrNotFound {
+		t.Errorf("deleting a deleted user should fail with %v, got %v", user.ErrNotFound, err)
+	}
+	if _, err := p.Users.FindByID(other.ID); err != nil {
+		t.Errorf("deletion removed an other user: %v", err)
+	}
+}
+
+func testUsersLogin(t *testing.T, p *Providers) {
+	u, _ := newUser(t, p)
+
+	if err := p.Users.Login(nil); err != user.ErrNil {
+		t.Errorf("login of a nil user should fail with %v, got %v", user.ErrNil, err)
+	}
+
+	before := time.Now().Add(-time.Second)
+	if err := p.Users.Login(u); err != nil {
+		t.Fatalf("unable to login: %v", err)
+	}
+	found, err := p.Users.FindByID(u.ID)
+	if err != nil {
+		t.Fatalf("unable to find user: %v", err)
+	}
+	if found.LoginFirst.Before(before) || found.LoginLast.Before(before) {
+		t.Errorf("login dates are not set: first %v, last %v", found.LoginFirst, found.LoginLast)
+	}
+
+	first := found.LoginFirst
+	if err = p.Users.Login(found); err != nil {
+		t.Fatalf("unable to login again: %v", err)
+	}
+	if found, err = p.Users.FindByID(u.ID); err != nil {
+		t.Fatalf("unable to find user: %v", err)
+	}
+	if !found.LoginFirst.Equal(first) {
+		t.Errorf("first login date changed from %v to %v", first, found.LoginFirst)
+	}
+}
+
+func testUsersKeys(t *testing.T, p *Providers) {
+	u, _ := newUser(t, p)
+	other, _ := newUser(t, p)
+	previous := u.PublicKeyDER
+
+	keys, err := p.Users.ListKeys(*u)
+	if err != nil {
+		t.Fatalf("unable to list keys: %v", err)
+	}
+	if len(keys) != 1 || !bytes.Equal(keys[0].PublicKeyDER, u.PublicKeyDER) || keys[0].IsRetired() {
+		t.Fatalf("created user should have its key as only key, got %d keys", len(keys))
+	}
+
+	if err = p.Users.RotateKey(nil, nil, "", time.Now()); err != user.ErrNil {
+		t.Errorf("rotating the key of a nil user should fail with %v, got %v", user.ErrNil, err)
+	}
+	if err = p.Users.RotateKey(u, other.PublicKeyDER, other.FingerPrint, time.Now()); err != user.ErrKeyUsed {
+		t.Errorf("rotating to the key of an other user should fail with %v, got %v", user.ErrKeyUsed, err)
+	}
+
+	newKey, _ := newUser(t, p)
+	if err = p.Users.Delete(newKey); err != nil {
+		t.Fatalf("unable to delete user: %v", err)
+	}
+	when := time.Now().UTC().Truncate(time.Second)
+	if err = p.Users.RotateKey(u, newKey.PublicKeyDER, newKey.FingerPrint, when); err != nil {
+		t.Fatalf("unable to rotate key: %v", err)
+	}
+	if !bytes.Equal(u.PublicKeyDER, newKey.PublicKeyDER) || u.FingerPrint != newKey.FingerPrint {
+		t.Error("rotated user key is not updated")
+	}
+	if found, err := p.Users.FindByPublicKeyDER(newKey.PublicKeyDER); err != nil || found.ID != u.ID {
+		t.Errorf("user should be found by its new key: %v, %v", found, err)
+	}
+	if _, err = p.Users.FindByPublicKeyDER(previous); err != user.ErrNotFound {
+		t.Errorf("user should not be found by its previous key, got %v", err)
+	}
+
+	if keys, err = p.Users.ListKeys(*u); err != nil {
+		t.Fatalf("unable to list keys: %v", err)
+	}
+	if len(keys) != 2 || !bytes.Equal(keys[0].PublicKeyDER, previous) || !bytes.Equal(keys[1].PublicKeyDER, newKey.PublicKeyDER) {
+		t.Fatalf("listed %d keys, expected the previous and the new key in addition order", len(keys))
+	}
+	if !keys[0].IsRetired() || !keys[0].Retired.Equal(when) || keys[1].IsRetired() || !keys[1].Added.Equal(when) {
+		t.Errorf("previous key should be retired when the new key is added: %+v, %+v", keys[0], keys[1])
+	}
+
+	if err = p.Users.RotateKey(u, previous, "", time.Now()); err != user.ErrKeyUsed {
+		t.Errorf("rotating back to a retired key should fail with %v, got %v", user.ErrKeyUsed, err)
+	}
+	if _, err = p.Users.Create(&user.User{PublicKeyDER: previous}); err != user.ErrKeyUsed {
+		t.Errorf("creating an user with a retired key should fail with %v, got %v", user.ErrKeyUsed, err)
+	}
+	if keys, err = p.Users.ListKeys(*other); err != nil || len(keys) != 1 {
+		t.Errorf("rotation changed the keys of an other user: %d keys, %v", len(keys), err)
+	}
+}
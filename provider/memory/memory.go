@@ -0,0 +1,187 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/krostar/nebulo-server/attachment"
+	"github.com/krostar/nebulo-server/certificate"
+	"github.com/krostar/nebulo-server/channel"
+	"github.com/krostar/nebulo-server/message"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// ErrStoreIsNil is throw when a memory provider is initialized before the store
+var ErrStoreIsNil = errors.New("memory store is nil")
+
+// Store keeps every rows in memory, it is the database shared by the memory providers,
+// rows are ordered by ID and every access must be done with the lock held
+type Store struct {
+	sync.RWMutex
+
+	Users        []user.User
+	UserKeys     []user.Key
+	Channels     []channel.Channel
+	Memberships  []channel.UserMembership
+	Messages     []message.Message
+	Attachments  []attachment.Attachment
+	Certificates []certificate.Certificate
+	CRLs         []certificate.CRL
+
+	lastIDs map[string]int
+}
+
+// S is the store used by the memory providers
+var S *Store
+
+// Use create an empty store and set it as the used store
+func Use() error {
+	S = New()
+	return nil
+}
+
+// New return an empty store
+func New() *Store {
+	return &Store{lastIDs: make(map[string]int)}
+}
+
+// NextID return a new identifier for a row of the table, identifiers are never reused
+func (s *Store) NextID(table string) int {
+	s.lastIDs[table]++
+	return s.lastIDs[table]
+}
+
+// DeleteUser remove an user and, like the foreign keys of the sql databases,
+// everything that belongs to him except the certificates issued to him
+func (s *Store) DeleteUser(id int) {
+	var created []int
+	for _, c := range s.Channels {
+		if c.CreatorID == id {
+			created = append(created, c.ID)
+		}
+	}
+	for _, channelID := range created {
+		s.DeleteChannel(channelID)
+	}
+
+	users := s.Users[:0]
+	for _, u := range s.Users {
+		if u.ID != id {
+			users = append(users, u)
+		}
+	}
+	s.Users = users
+
+	keys := s.UserKeys[:0]
+	for _, k := range s.UserKeys {
+		if k.UserID != id {
+			keys = append(keys, k)
+		}
+	}
+	s.UserKeys = keys
+
+	memberships := s.Memberships[:0]
+	for _, um := range s.Memberships {
+		if um.UserID != id {
+			memberships = append(memberships, um)
+		}
+	}
+	s.Memberships = memberships
+
+	messages := s.Messages[:0]
+	for _, m := range s.Messages {
+		if m.SenderID != id && m.ReceiverID != id {
+			messages = append(messages, m)
+		}
+	}
+	s.Messages = messages
+
+	attachments := s.Attachments[:0]
+	for _, a := range s.Attachments {
+		if a.OwnerID != id {
+			attachments = append(attachments, a)
+		}
+	}
+	s.Attachments = attachments
+}
+
+// DeleteChannel remove a channel with all its memberships, messages and attachments
+func (s *Store) DeleteChannel(id int) {
+	channels := s.Channels[:0]
+	for _, c := range s.Channels {
+		if c.ID != id {
+			channels = append(channels, c)
+		}
+	}
+	s.Channels = channels
+
+	memberships := s.Memberships[:0]
+	for _, um := range s.Memberships {
+		if um.ChannelID != id {
+			memberships = append(memberships, um)
+		}
+	}
+	s.Memberships = memberships
+
+	messages := s.Messages[:0]
+	for _, m := range s.Messages {
+		if m.ChannelID != id {
+			messages = append(messages, m)
+		}
+	}
+	s.Messages = messages
+
+	attachments := s.Attachments[:0]
+	for _, a := range s.Attachments {
+		if a.ChannelID != id {
+			attachments = append(attachments, a)
+		}
+	}
+	s.Attachments = attachments
+}
+
+// SetColumns update the fields of a model from their gorm column name,
+// the same way the sql providers update a row from a map of columns
+func SetColumns(model interface{}, columns map[string]interface{}) error {
+	v := reflect.Indirect(reflect.ValueOf(model))
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("unable to set columns of a %s", v.Kind())
+	}
+
+	for column, value := range columns {
+		field, ok := fieldByColumn(v, column)
+		if !ok {
+			return fmt.Errorf("unknown column %q", column)
+		}
+
+		nv := reflect.ValueOf(value)
+		switch {
+		case !nv.IsValid():
+			field.Set(reflect.Zero(field.Type()))
+		case nv.Type().ConvertibleTo(field.Type()):
+			field.Set(nv.Convert(field.Type()))
+		case field.Kind() == reflect.Ptr && nv.Type().ConvertibleTo(field.Type().Elem()):
+			ptr := reflect.New(field.Type().Elem())
+			ptr.Elem().Set(nv.Convert(field.Type().Elem()))
+			field.Set(ptr)
+		default:
+			return fmt.Errorf("unable to set column %q of type %s with a %s", column, field.Type(), nv.Type())
+		}
+	}
+	return nil
+}
+
+func fieldByColumn(v reflect.Value, column string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		for _, setting := range strings.Split(t.Field(i).Tag.Get("gorm"), ";") {
+			if strings.TrimSpace(setting) == "column:"+column {
+				return v.Field(i), true
+			}
+		}
+	}
+	return reflect.Value{}, false
+}
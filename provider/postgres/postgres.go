@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/lib/pq" // postgres driver used by gorm
+
+	gp "github.com/krostar/nebulo-golib/provider"
+)
+
+// Config contains the informations needed to connect to a PostgreSQL database
+type Config struct {
+	gp.DefaultConfig `json:"-"`
+	Username         string `json:"username"`
+	Password         string `json:"password"`
+	Address          string `json:"address"`
+	Database         string `json:"database"`
+	SSLMode          string `json:"sslmode" validate:"regexp=^(disable|require|verify-ca|verify-full)?$"`
+}
+
+// DSN return the connection string matching the configuration
+func (c *Config) DSN() string {
+	sslMode := c.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.Username, c.Password),
+		Host:     c.Address,
+		Path:     "/" + c.Database,
+		RawQuery: url.Values{"sslmode": []string{sslMode}}.Encode(),
+	}
+	return dsn.String()
+}
+
+// Use open a connection to a PostgreSQL database and set it as the root provider
+func Use(c *Config) (err error) {
+	db, err := gorm.Open("postgres", c.DSN())
+	if err != nil {
+		return fmt.Errorf("unable to open postgres database: %v", err)
+	}
+	if err = db.DB().Ping(); err != nil {
+		if errClose := db.Close(); errClose != nil {
+			return fmt.Errorf("unable to reach postgres database: %v (and unable to close it: %v)", err, errClose)
+		}
+		return fmt.Errorf("unable to reach postgres database: %v", err)
+	}
+
+	gp.RP = &gp.RootProvider{DB: db}
+	return nil
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+
+	"github.com/krostar/nebulo-server/attachment"
+	ap "github.com/krostar/nebulo-server/attachment/provider"
+	"github.com/krostar/nebulo-server/config"
+	"github.com/krostar/nebulo-server/identifier"
+)
+
+// ChanAttachmentCreateRequest store the request body for a ChanAttachmentCreate request
+type ChanAttachmentCreateRequest struct {
+	Size int64 `json:"size"`
+}
+
+// ChanAttachmentCreate handle the route POST /chan/:chan/attachment.
+// Register a new attachment waiting to be uploaded
+/**
+ * @api {post} /chan/:chan/attachment Create an attachment
+ * @apiDescription Register a new attachment, encrypted by the client, before uploading its content.
+ * The returned identifier can be embedded in messages to refer to the attachment.
+ * The size of every attachments of an user, complete or not, is limited by a quota.
+ * @apiName Attachment - Create
+ * @apiGroup Attachment
+ *
+ * @apiParam {String} chan public identifier of the channel
+ * @apiParam {Number} size size in bytes of the encrypted attachment
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X POST -v --cert bob.crt --key bob.key "https://api.nebulo.io/chan/yDDvaSdT1hlUXq6nJ0q9Pg/attachment" --data "{\"size\": 1048576}"
+ *
+ * @apiSuccess (Success) {json} 201 Created
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 201 "Created"
+ *		{
+ *			"id": "Vd0zkC7q2mpZ3WkM5YxOwA",
+ *			"owner": {...},
+ *			"size": 1048576,
+ *			"received": 0,
+ *			"created": "2017-05-11T10:15:55Z",
+ *			"completed": null
+ *		}
+ *
+ * @apiError (Errors 4XX) {json} 400 Bad request: bad json input or invalid size
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate
+ * @apiError (Errors 4XX) {json} 404 Not found: user or channel not found
+ * @apiError (Errors 4XX) {json} 413 Request entity too large: attachment too large or quota exceeded
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func ChanAttachmentCreate(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	// bind the request body to the struct
+	r := ChanAttachmentCreateRequest{}
+	if err = c.Bind(&r); err != nil {
+		return httperror.HTTPBadRequestError(err)
+	}
+	if r.Size <= 0 {
+		return httperror.New(http.StatusBadRequest, "size", httperror.BadParam("size must be positive"))
+	}
+	if maxSize := config.Config.Run.Attachments.MaxSize; r.Size > maxSize {
+		return httperror.New(http.StatusRequestEntityTooLarge, "size",
+			httperror.BadParam(fmt.Sprintf("attachments can't be larger than %d bytes", maxSize)),
+		)
+	}
+
+	chnel, err := getChannel(u, c.Param("chan"))
+	if err != nil {
+		return err
+	}
+
+	publicID, err := identifier.New()
+	if err != nil {
+		return httperror.HTTPInternalServerError(fmt.Errorf("unable to generate attachment public id: %v", err))
+	}
+	quota := config.Config.Run.Attachments.Quota
+	a, err := ap.P.Create(*u, *chnel, publicID, r.Size, quota)
+	if err == attachment.ErrQuotaExceeded {
+		return httperror.New(http.StatusRequestEntityTooLarge, "size",
+			httperror.BadParam(fmt.Sprintf("quota of %d bytes exceeded", quota)),
+		)
+	} else if err != nil {
+		return httperror.HTTPInternalServerError(fmt.Errorf("unable to create attachment: %v", err))
+	}
+
+	return c.JSONPretty(http.StatusCreated, a, "    ")
+}
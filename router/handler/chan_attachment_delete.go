@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+
+	ap "github.com/krostar/nebulo-server/attachment/provider"
+	"github.com/krostar/nebulo-server/attachment/storage"
+)
+
+// ChanAttachmentDelete handle the route DELETE /chan/:chan/attachment/:attachment.
+// Delete an attachment and its content
+/**
+ * @api {delete} /chan/:chan/attachment/:attachment Delete an attachment
+ * @apiDescription Delete an attachment and its content, only the owner of the attachment can delete it.
+ * @apiName Attachment - Delete
+ * @apiGroup Attachment
+ *
+ * @apiParam {String} chan public identifier of the channel
+ * @apiParam {String} attachment public identifier of the attachment
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X DELETE -v --cert bob.crt --key bob.key "https://api.nebulo.io/chan/yDDvaSdT1hlUXq6nJ0q9Pg/attachment/Vd0zkC7q2mpZ3WkM5YxOwA"
+ *
+ * @apiSuccess (Success) {nothing} 204 No Content
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 204 "No Content"
+ *
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate or not the owner of the attachment
+ * @apiError (Errors 4XX) {json} 404 Not found: user, channel or attachment not found
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func ChanAttachmentDelete(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	chnel, err := getChannel(u, c.Param("chan"))
+	if err != nil {
+		return err
+	}
+	a, err := getAttachment(chnel, c.Param("attachment"))
+	if err != nil {
+		return err
+	}
+	if a.OwnerID != u.ID {
+		return httperror.HTTPUnauthorizedError(errors.New("only the owner can delete the attachment"))
+	}
+
+	if err = ap.P.Delete(a); err != nil {
+		return httperror.HTTPInternalServerError(err)
+	}
+	if err = storage.S.Delete(a.PublicID); err != nil {
+		return httperror.HTTPInternalServerError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
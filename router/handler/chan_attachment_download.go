@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/krostar/nebulo-golib/log"
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+
+	"github.com/krostar/nebulo-server/attachment/storage"
+)
+
+// ChanAttachmentDownload handle the route GET /chan/:chan/attachment/:attachment.
+// Return the encrypted content of an attachment
+/**
+ * @api {get} /chan/:chan/attachment/:attachment Download an attachment
+ * @apiDescription Return the encrypted content of a complete attachment, only members of the channel
+ * can download it. The Range header is supported to resume a download.
+ * @apiName Attachment - Download
+ * @apiGroup Attachment
+ *
+ * @apiParam {String} chan public identifier of the channel
+ * @apiParam {String} attachment public identifier of the attachment
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X GET -v --cert bob.crt --key bob.key -o attachment.bin "https://api.nebulo.io/chan/yDDvaSdT1hlUXq6nJ0q9Pg/attachment/Vd0zkC7q2mpZ3WkM5YxOwA"
+ *
+ * @apiSuccess (Success) {binary} 200 OK
+ *
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate
+ * @apiError (Errors 4XX) {json} 404 Not found: user, channel or attachment not found, or attachment not complete
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func ChanAttachmentDownload(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	chnel, err := getChannel(u, c.Param("chan"))
+	if err != nil {
+		return err
+	}
+	a, err := getAttachment(chnel, c.Param("attachment"))
+	if err != nil {
+		return err
+	}
+	if !a.IsComplete() {
+		return httperror.HTTPNotFoundError(errors.New("attachment upload is not complete"))
+	}
+
+	content, err := storage.S.Open(a.PublicID)
+	if err == storage.ErrNotFound {
+		return httperror.HTTPNotFoundError(err)
+	} else if err != nil {
+		return httperror.HTTPInternalServerError(err)
+	}
+	defer func() {
+		if errClose := content.Close(); errClose != nil {
+			log.Warningf("unable to close attachment %q: %v", a.PublicID, errClose)
+		}
+	}()
+
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMEOctetStream)
+	http.ServeContent(c.Response(), c.Request(), "", *a.Completed, content)
+	return nil
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+)
+
+// ChanAttachmentInfos handle the route GET /chan/:chan/attachment/:attachment/infos.
+// Return the informations of an attachment
+/**
+ * @api {get} /chan/:chan/attachment/:attachment/infos Get attachment infos
+ * @apiDescription Return the informations of an attachment, like the number of bytes already received
+ * to resume an upload.
+ * @apiName Attachment - Infos
+ * @apiGroup Attachment
+ *
+ * @apiParam {String} chan public identifier of the channel
+ * @apiParam {String} attachment public identifier of the attachment
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X GET -v --cert bob.crt --key bob.key "https://api.nebulo.io/chan/yDDvaSdT1hlUXq6nJ0q9Pg/attachment/Vd0zkC7q2mpZ3WkM5YxOwA/infos"
+ *
+ * @apiSuccess (Success) {json} 200 OK
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 200 "OK"
+ *		{
+ *			"id": "Vd0zkC7q2mpZ3WkM5YxOwA",
+ *			"owner": {...},
+ *			"size": 1048576,
+ *			"received": 524288,
+ *			"created": "2017-05-11T10:15:55Z",
+ *			"completed": null
+ *		}
+ *
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate
+ * @apiError (Errors 4XX) {json} 404 Not found: user, channel or attachment not found
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func ChanAttachmentInfos(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	chnel, err := getChannel(u, c.Param("chan"))
+	if err != nil {
+		return err
+	}
+	a, err := getAttachment(chnel, c.Param("attachment"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSONPretty(http.StatusOK, a, "    ")
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+
+	"github.com/krostar/nebulo-server/attachment"
+	ap "github.com/krostar/nebulo-server/attachment/provider"
+	"github.com/krostar/nebulo-server/attachment/storage"
+)
+
+// headerUploadOffset is the offset of the uploaded chunk in the attachment
+const headerUploadOffset = "Upload-Offset"
+
+// uploads are the public identifiers of the attachments being written, the received size
+// is claimed before writing a chunk so concurrent chunks at the same offset can't both be written
+var uploads = struct {
+	sync.Mutex
+	inProgress map[string]bool
+}{inProgress: make(map[string]bool)}
+
+// ChanAttachmentUpload handle the route PATCH /chan/:chan/attachment/:attachment.
+// Append a chunk of content to an attachment
+/**
+ * @api {patch} /chan/:chan/attachment/:attachment Upload an attachment chunk
+ * @apiDescription Append the request body to the content of the attachment. The Upload-Offset header must be
+ * equal to the number of bytes already received, it allows to resume an interrupted upload
+ * by fetching the attachment infos first. The response Upload-Offset header contains the new received size.
+ * Only the owner of the attachment can upload its content.
+ * @apiName Attachment - Upload
+ * @apiGroup Attachment
+ *
+ * @apiParam {String} chan public identifier of the channel
+ * @apiParam {String} attachment public identifier of the attachment
+ * @apiHeader {Number} Upload-Offset number of bytes already received
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X PATCH -v --cert bob.crt --key bob.key -H "Upload-Offset: 0" --data-binary @chunk.bin "https://api.nebulo.io/chan/yDDvaSdT1hlUXq6nJ0q9Pg/attachment/Vd0zkC7q2mpZ3WkM5YxOwA"
+ *
+ * @apiSuccess (Success) {nothing} 204 No Content
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 204 "No Content"
+ *		Upload-Offset: 524288
+ *
+ * @apiError (Errors 4XX) {json} 400 Bad request: unable to parse Upload-Offset or attachment already complete
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate or not the owner of the attachment
+ * @apiError (Errors 4XX) {json} 404 Not found: user, channel or attachment not found
+ * @apiError (Errors 4XX) {json} 409 Conflict: Upload-Offset does not match the received size, or another chunk is being uploaded
+ * @apiError (Errors 4XX) {json} 413 Request entity too large: chunk larger than the remaining size
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func ChanAttachmentUpload(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	offset, err := strconv.ParseInt(c.Request().Header.Get(headerUploadOffset), 10, 64)
+	if err != nil {
+		return httperror.HTTPBadRequestError(fmt.Errorf("unable to parse %s: %v", headerUploadOffset, err))
+	}
+
+	chnel, err := getChannel(u, c.Param("chan"))
+	if err != nil {
+		return err
+	}
+	// the attachment is loaded once claimed to get the received size no other chunk can change
+	if !claimUpload(c.Param("attachment")) {
+		return httperror.New(http.StatusConflict, headerUploadOffset, httperror.BadParam(attachment.ErrUploadInProgress.Error()))
+	}
+	defer releaseUpload(c.Param("attachment"))
+	a, err := getAttachment(chnel, c.Param("attachment"))
+	if err != nil {
+		return err
+	}
+
+	if a.OwnerID != u.ID {
+		return httperror.HTTPUnauthorizedError(errors.New("only the owner can upload the attachment"))
+	}
+	if a.IsComplete() {
+		return httperror.HTTPBadRequestError(errors.New("attachment already complete"))
+	}
+	c.Response().Header().Set(headerUploadOffset, strconv.FormatInt(a.Received, 10))
+	if offset != a.Received {
+		return httperror.New(http.StatusConflict, headerUploadOffset, httperror.BadParam(attachment.ErrOffsetMismatch.Error()))
+	}
+	remaining := a.Size - a.Received
+	if c.Request().ContentLength > remaining {
+		return httperror.New(http.StatusRequestEntityTooLarge, "body",
+			httperror.BadParam(fmt.Sprintf("only %d bytes remains to be uploaded", remaining)),
+		)
+	}
+
+	// what has been written is kept even if the client went away, he can resume from there
+	written, errWrite := storage.S.WriteAt(a.PublicID, offset, io.LimitReader(c.Request().Body, remaining))
+	if written > 0 {
+		if err = ap.P.Received(a, offset, offset+written); err == attachment.ErrOffsetMismatch {
+			return httperror.New(http.StatusConflict, headerUploadOffset, httperror.BadParam(err.Error()))
+		} else if err != nil {
+			return httperror.HTTPInternalServerError(err)
+		}
+	}
+	if errWrite != nil {
+		return httperror.HTTPInternalServerError(errWrite)
+	}
+
+	c.Response().Header().Set(headerUploadOffset, strconv.FormatInt(a.Received, 10))
+	return c.NoContent(http.StatusNoContent)
+}
+
+// claimUpload return false if a chunk of the attachment is already being uploaded
+func claimUpload(publicID string) bool {
+	uploads.Lock()
+	defer uploads.Unlock()
+	if uploads.inProgress[publicID] {
+		return false
+	}
+	uploads.inProgress[publicID] = true
+	return true
+}
+
+// releaseUpload allow the next chunk of the attachment to be uploaded
+func releaseUpload(publicID string) {
+	uploads.Lock()
+	defer uploads.Unlock()
+	delete(uploads.inProgress, publicID)
+}
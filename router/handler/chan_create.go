@@ -9,6 +9,7 @@ import (
 	"github.com/labstack/echo"
 
 	cp "github.com/krostar/nebulo-server/channel/provider"
+	"github.com/krostar/nebulo-server/event"
 	"github.com/krostar/nebulo-server/user"
 	up "github.com/krostar/nebulo-server/user/provider"
 )
@@ -43,6 +44,15 @@ func ChanCreate(c echo.Context) (err error) {
 		return httperror.HTTPInternalServerError(fmt.Errorf("unable to create channel: %v", err))
 	}
 
+	// notify every members of the new channel
+	for _, m := range members {
+		eventType := event.MembershipCreated
+		if m.ID == u.ID {
+			eventType = event.ChannelCreated
+		}
+		event.H.Publish(event.New(eventType, newChannel.PublicID, newChannel), m.ID)
+	}
+
 	return c.JSONPretty(http.StatusOK, newChannel, "    ")
 }
 
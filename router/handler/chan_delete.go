@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/krostar/nebulo-golib/log"
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+
+	ap "github.com/krostar/nebulo-server/attachment/provider"
+	"github.com/krostar/nebulo-server/attachment/storage"
+	cp "github.com/krostar/nebulo-server/channel/provider"
+	"github.com/krostar/nebulo-server/event"
+)
+
+// ChanDelete handle the route DELETE /chan/:chan.
+// Delete a channel with all its memberships, messages and attachments
+/**
+ * @api {delete} /chan/:chan Delete a channel
+ * @apiDescription Delete the channel, its memberships and every messages and attachments posted in it.
+ * Only the creator of the channel can delete it.
+ * @apiName Channel - Delete
+ * @apiGroup Channel
+ *
+ * @apiParam {String} chan public identifier of the channel
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X DELETE -v --cert bob.crt --key bob.key "https://api.nebulo.io/chan/yDDvaSdT1hlUXq6nJ0q9Pg"
+ *
+ * @apiSuccess (Success) {nothing} 204 No Content
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 204 "No Content"
+ *
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate or not the creator of the channel
+ * @apiError (Errors 4XX) {json} 404 Not found: user or channel not found
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func ChanDelete(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	chann, err := getChannel(u, c.Param("chan"))
+	if err != nil {
+		return err
+	}
+
+	if chann.CreatorID != u.ID {
+		return httperror.HTTPUnauthorizedError(errors.New("only the creator can delete this channel"))
+	}
+
+	// attachments contents are not stored in database, they are removed once the channel is deleted
+	attachments, err := ap.P.ListByChannel(*chann)
+	if err != nil {
+		return httperror.HTTPInternalServerError(fmt.Errorf("unable to list channel attachments: %v", err))
+	}
+	if err = ap.P.DeleteByChannel(*chann); err != nil {
+		return httperror.HTTPInternalServerError(err)
+	}
+	if err = cp.P.Delete(chann); err != nil {
+		return httperror.HTTPInternalServerError(fmt.Errorf("unable to delete channel: %v", err))
+	}
+	for _, a := range attachments {
+		if err = storage.S.Delete(a.PublicID); err != nil {
+			log.Warningf("unable to delete attachment %q content: %v", a.PublicID, err)
+		}
+	}
+	event.H.Publish(event.New(event.ChannelDeleted, chann.PublicID, chann), membersID(chann)...)
+
+	return c.NoContent(http.StatusNoContent)
+}
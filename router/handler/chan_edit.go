@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+
+	"github.com/krostar/nebulo-server/channel"
+	cp "github.com/krostar/nebulo-server/channel/provider"
+	"github.com/krostar/nebulo-server/event"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// ChanEditRequest store the request body for a ChanEdit request,
+// only non-null fields are updated
+type ChanEditRequest struct {
+	Name             *string `json:"name"`
+	MembersCanEdit   *bool   `json:"members_can_edit"`
+	MembersCanInvite *bool   `json:"members_can_invite"`
+	MessagesTTL      *int    `json:"messages_ttl"`
+}
+
+// ChanEdit handle the route PUT /chan/:chan.
+// Perform a channel modification and return the whole channel
+/**
+ * @api {put} /chan/:chan Update channel infos
+ * @apiDescription Perform a channel modification and return the whole channel.
+ * The name can be updated by the creator, or by any members if "members_can_edit" is true.
+ * The "members_can_edit" and "members_can_invite" flags can only be updated by the creator.
+ * The "messages_ttl" is the number of seconds messages are kept, 0 to keep them forever, it can only
+ * be updated by the creator and only apply to messages posted after the update.
+ * @apiName Channel - Update infos
+ * @apiGroup Channel
+ *
+ * @apiParam {String} chan public identifier of the channel
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X PUT -v --cert bob.crt --key bob.key "https://api.nebulo.io/chan/yDDvaSdT1hlUXq6nJ0q9Pg" --data "{\"name\": \"friends\", \"members_can_edit\": true}"
+ *
+ * @apiSuccess (Success) {nothing} 200 OK
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 200 "OK"
+ *		{
+ *			"id": "yDDvaSdT1hlUXq6nJ0q9Pg",
+ *			"name": "friends",
+ *			"created": "2017-05-11T10:15:55Z",
+ *			"creator": {...},
+ *			"members": [{...}, {...}],
+ *			"members_can_edit": true,
+ *			"members_can_invite": false,
+ *			"messages_ttl": 0
+ *		}
+ *
+ * @apiError (Errors 4XX) {json} 400 Bad request: bad json input, name already used or negative messages ttl
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate or not allowed to edit the channel
+ * @apiError (Errors 4XX) {json} 404 Not found: user or channel not found
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func ChanEdit(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	chann, err := getChannel(u, c.Param("chan"))
+	if err != nil {
+		return err
+	}
+
+	// bind the request body to the struct
+	r := &ChanEditRequest{}
+	if err = c.Bind(r); err != nil {
+		return httperror.HTTPBadRequestError(err)
+	}
+
+	fields, err := chanEditChecks(*u, *chann, *r)
+	if err != nil {
+		return err
+	}
+
+	if len(fields) > 0 {
+		if err = cp.P.Update(chann, fields); err != nil {
+			return httperror.HTTPInternalServerError(fmt.Errorf("unable to save channel: %v", err))
+		}
+	}
+
+	// reload the channel to send back the up-to-date version
+	if chann, err = cp.P.FindByPublicID(*u, chann.PublicID); err != nil {
+		return httperror.HTTPInternalServerError(fmt.Errorf("unable to reload channel: %v", err))
+	}
+	event.H.Publish(event.New(event.ChannelUpdated, chann.PublicID, chann), membersID(chann)...)
+
+	return c.JSONPretty(http.StatusOK, chann, "    ")
+}
+
+func chanEditChecks(u user.User, chann channel.Channel, r ChanEditRequest) (fields map[string]interface{}, err error) {
+	isCreator := chann.CreatorID == u.ID
+	fields = make(map[string]interface{})
+
+	if r.Name != nil && *r.Name != chann.Name {
+		if !isCreator && !chann.MembersCanEdit {
+			return nil, httperror.HTTPUnauthorizedError(errors.New("only the creator can edit this channel"))
+		}
+		if len(*r.Name) == 0 || len(*r.Name) > 64 {
+			return nil, httperror.New(http.StatusBadRequest, "name",
+				httperror.BadParam("name length must be between 1 and 64"),
+			)
+		}
+
+		// names are uniq per creator
+		_, err = cp.P.Find(channel.Channel{Name: *r.Name, CreatorID: chann.CreatorID})
+		if err == nil {
+			return nil, httperror.New(http.StatusBadRequest, "name",
+				httperror.BadParam(fmt.Sprintf("a channel named %q already exist", *r.Name)),
+			)
+		} else if err != channel.ErrNotFound {
+			return nil, httperror.HTTPInternalServerError(fmt.Errorf("unable to find channel: %v", err))
+		}
+		fields["name"] = *r.Name
+	}
+
+	if r.MembersCanEdit != nil && *r.MembersCanEdit != chann.MembersCanEdit {
+		if !isCreator {
+			return nil, httperror.HTTPUnauthorizedError(errors.New("only the creator can change members permissions"))
+		}
+		fields["members_can_edit"] = *r.MembersCanEdit
+	}
+	if r.MembersCanInvite != nil && *r.MembersCanInvite != chann.MembersCanInvite {
+		if !isCreator {
+			return nil, httperror.HTTPUnauthorizedError(errors.New("only the creator can change members permissions"))
+		}
+		fields["members_can_invite"] = *r.MembersCanInvite
+	}
+	if r.MessagesTTL != nil && *r.MessagesTTL != chann.MessagesTTL {
+		if !isCreator {
+			return nil, httperror.HTTPUnauthorizedError(errors.New("only the creator can change messages retention"))
+		}
+		if *r.MessagesTTL < 0 {
+			return nil, httperror.New(http.StatusBadRequest, "messages_ttl",
+				httperror.BadParam("messages_ttl can't be negative"),
+			)
+		}
+		fields["messages_ttl"] = *r.MessagesTTL
+	}
+
+	return fields, nil
+}
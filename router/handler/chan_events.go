@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+
+	"github.com/krostar/nebulo-server/event"
+)
+
+const (
+	// send a comment to keep the connection alive through proxies with this period
+	sseHeartbeatPeriod = 30 * time.Second
+	// delay the client should wait before reconnecting, in milliseconds
+	sseRetry = 5000
+	// number of events kept for a slow client before disconnecting it
+	sseEventsBuffer = 64
+)
+
+// ChanEvents handle the route GET /chan/events.
+// Stream the events of the logged user with server-sent events
+/**
+ * @api {get} /chan/events Real-time events stream
+ * @apiDescription Stream new messages, channels and memberships events of the user
+ * using server-sent events, for clients that can't use the websocket endpoint.
+ * Each event id can be sent back in the Last-Event-ID header while reconnecting to receive missed events.
+ * If some events are too old to be sent back or the id is unknown, a "reset" event is sent first
+ * and the client should fetch the channels and messages lists again.
+ * @apiName Events - Server-sent events
+ * @apiGroup Events
+ *
+ * @apiHeader {String} [Last-Event-ID] id of the last event received
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X GET -v -N --cert bob.crt --key bob.key -H "Last-Event-ID: 41" "https://api.nebulo.io/chan/events"
+ *
+ * @apiSuccess (Success) {nothing} 200 OK
+ * @apiSuccessExample {text} Success example
+ *		HTTP/1.1 200 "OK"
+ *		retry: 5000
+ *
+ *		id: 42
+ *		event: message_created
+ *		data: {"id":42,"type":"message_created","channel":"yDDvaSdT1hlUXq6nJ0q9Pg","data":{...}}
+ *
+ * @apiError (Errors 4XX) {json} 400 Bad request: unable to parse Last-Event-ID
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate
+ * @apiError (Errors 4XX) {json} 404 Not found: user not found
+ */
+func ChanEvents(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	var since uint64
+	if lastEventID := c.Request().Header.Get("Last-Event-ID"); lastEventID != "" {
+		if since, err = strconv.ParseUint(lastEventID, 10, 64); err != nil {
+			return httperror.HTTPBadRequestError(fmt.Errorf("unable to parse Last-Event-ID %q: %v", lastEventID, err))
+		}
+	}
+
+	sub, missed, complete := event.H.SubscribeSince(u.ID, sseEventsBuffer, since)
+	defer event.H.Unsubscribe(sub)
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	if _, err = fmt.Fprintf(res, "retry: %d\n\n", sseRetry); err != nil {
+		return nil
+	}
+	if since != 0 && !complete {
+		if _, err = fmt.Fprint(res, "event: reset\ndata: {}\n\n"); err != nil {
+			return nil
+		}
+	}
+	for _, e := range missed {
+		if err = sseWriteEvent(res, e); err != nil {
+			return nil
+		}
+	}
+	res.Flush()
+
+	ticker := time.NewTicker(sseHeartbeatPeriod)
+	defer ticker.Stop()
+
+	// the response is already sent, errors are only the client going away
+	for {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			if err = sseWriteEvent(res, e); err != nil {
+				return nil
+			}
+		case <-ticker.C:
+			if _, err = fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+		case <-c.Request().Context().Done():
+			return nil
+		}
+		res.Flush()
+	}
+}
+
+func sseWriteEvent(res *echo.Response, e *event.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("unable to marshal event %d: %v", e.ID, err)
+	}
+	_, err = fmt.Fprintf(res, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
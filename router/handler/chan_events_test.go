@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+
+	"github.com/krostar/nebulo-server/event"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// streamEvents request the events of the user with the Last-Event-ID header, if not empty;
+// the client is gone before the request is handled, only the events sent on connection are returned
+func streamEvents(u *user.User, lastEventID string) (code int, ids []string, reset bool) {
+	req := httptest.NewRequest(echo.GET, "/chan/events", nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	cancel()
+	rec := httptest.NewRecorder()
+
+	c := echo.New().NewContext(req.WithContext(ctx), rec)
+	c.Set("user", u)
+	if err := ChanEvents(c); err != nil {
+		httperror.ErrorHandler(err, c)
+	}
+
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if strings.HasPrefix(line, "id: ") {
+			ids = append(ids, strings.TrimPrefix(line, "id: "))
+		}
+		reset = reset || line == "event: reset"
+	}
+	return rec.Code, ids, reset
+}
+
+func TestChanEventsLastEventID(t *testing.T) {
+	defer func(h *event.Hub) { event.H = h }(event.H)
+	event.H = event.NewHub(2)
+
+	u := &user.User{ID: 1}
+	var published []string
+	for i := 0; i < 3; i++ {
+		e := event.New(event.MessageCreated, "chan", i)
+		event.H.Publish(e, u.ID)
+		published = append(published, fmt.Sprint(e.ID))
+	}
+	// the next event ID, which will be given to an other user
+	future := fmt.Sprint(event.New(event.MessageCreated, "chan", nil).ID)
+
+	tests := []struct {
+		name        string
+		lastEventID string
+		code        int
+		ids         []string
+		reset       bool
+	}{
+		{"without id", "", http.StatusOK, published[1:], false},
+		{"malformed", "forty-two", http.StatusBadRequest, nil, false},
+		{"negative", "-1", http.StatusBadRequest, nil, false},
+		{"up to date", published[2], http.StatusOK, nil, false},
+		{"missed one", published[1], http.StatusOK, published[2:], false},
+		{"missed every remembered ones", published[0], http.StatusOK, published[1:], false},
+		{"older than the history", "1", http.StatusOK, published[1:], true},
+		// the IDs start from the current time, an ID seen before a restart
+		// with the clock set back is newer than the last one given
+		{"newer than the last given", future + "000", http.StatusOK, published[1:], true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			code, ids, reset := streamEvents(u, tt.lastEventID)
+			if code != tt.code {
+				t.Fatalf("response status should be %d, got %d", tt.code, code)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.ids, ",") {
+				t.Errorf("sent events should be %v, got %v", tt.ids, ids)
+			}
+			if reset != tt.reset {
+				t.Errorf("reset event sent should be %t, got %t", tt.reset, reset)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+)
+
+// ChanInfos handle the route GET /chan/:chan.
+// Return the informations of a channel the logged user is member of
+/**
+ * @api {get} /chan/:chan Get channel infos
+ * @apiDescription Return the informations of a channel, including its creator and members
+ * @apiName Channel - Get infos
+ * @apiGroup Channel
+ *
+ * @apiParam {String} chan public identifier of the channel
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X GET -v --cert bob.crt --key bob.key "https://api.nebulo.io/chan/yDDvaSdT1hlUXq6nJ0q9Pg"
+ *
+ * @apiSuccess (Success) {nothing} 200 OK
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 200 "OK"
+ *		{
+ *			"id": "yDDvaSdT1hlUXq6nJ0q9Pg",
+ *			"name": "bob and alice",
+ *			"created": "2017-05-11T10:15:55Z",
+ *			"creator": {...},
+ *			"members": [{...}, {...}],
+ *			"members_can_edit": false,
+ *			"members_can_invite": false
+ *		}
+ *
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate
+ * @apiError (Errors 4XX) {json} 404 Not found: user or channel not found
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func ChanInfos(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	chann, err := getChannel(u, c.Param("chan"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSONPretty(http.StatusOK, chann, "    ")
+}
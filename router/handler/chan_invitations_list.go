@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+
+	cp "github.com/krostar/nebulo-server/channel/provider"
+)
+
+// ChanInvitationsList handle the route GET /chan/invitations.
+// Return the pending invitations of the logged user
+/**
+ * @api {get} /chan/invitations List pending invitations
+ * @apiDescription Return the channels the user has been invited in but did not join yet
+ * @apiName Channel - List invitations
+ * @apiGroup Channel
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X GET -v --cert bob.crt --key bob.key "https://api.nebulo.io/chan/invitations"
+ *
+ * @apiSuccess (Success) {nothing} 200 OK
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 200 "OK"
+ *		[
+ *			{
+ *				"user": {...},
+ *				"channel": {...},
+ *				"invited": "2017-05-11T10:15:55Z",
+ *				"joined": null
+ *			}
+ *		]
+ *
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate
+ * @apiError (Errors 4XX) {json} 404 Not found: user not found
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func ChanInvitationsList(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	list, err := cp.P.ListInvitations(*u)
+	if err != nil {
+		return httperror.HTTPInternalServerError(err)
+	}
+
+	return c.JSONPretty(http.StatusOK, list, "    ")
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+
+	"github.com/krostar/nebulo-server/channel"
+	cp "github.com/krostar/nebulo-server/channel/provider"
+	"github.com/krostar/nebulo-server/event"
+	up "github.com/krostar/nebulo-server/user/provider"
+)
+
+// ChanInviteRequest store the request body for a ChanInvite request
+type ChanInviteRequest struct {
+	Member string `json:"member_public_key"`
+}
+
+// ChanInvite handle the route POST /chan/:chan/members.
+// Invite an user in a channel, the user has to accept the invitation to become a member
+/**
+ * @api {post} /chan/:chan/members Invite an user
+ * @apiDescription Invite an user, identified by his public key, in the channel.
+ * The invited user won't receive any message until he joins the channel.
+ * The creator can always invite users, members can only if "members_can_invite" is true.
+ * @apiName Channel - Invite
+ * @apiGroup Channel
+ *
+ * @apiParam {String} chan public identifier of the channel
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X POST -v --cert bob.crt --key bob.key "https://api.nebulo.io/chan/yDDvaSdT1hlUXq6nJ0q9Pg/members" --data "{\"member_public_key\": \"MIICIjANBgkqhkiG9w0BAQEFAAOCAg8AMIICCgKCAgEA...\"}"
+ *
+ * @apiSuccess (Success) {nothing} 201 Created
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 201 "Created"
+ *		{
+ *			"user": {...},
+ *			"channel": {...},
+ *			"invited": "2017-05-11T10:15:55Z",
+ *			"joined": null
+ *		}
+ *
+ * @apiError (Errors 4XX) {json} 400 Bad request: bad json input, unknown user or user already invited
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate or not allowed to invite
+ * @apiError (Errors 4XX) {json} 404 Not found: user or channel not found
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func ChanInvite(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	chann, err := getChannel(u, c.Param("chan"))
+	if err != nil {
+		return err
+	}
+
+	if chann.CreatorID != u.ID && !chann.MembersCanInvite {
+		return httperror.HTTPUnauthorizedError(errors.New("only the creator can invite users in this channel"))
+	}
+
+	// bind the request body to the struct
+	r := &ChanInviteRequest{}
+	if err = c.Bind(r); err != nil {
+		return httperror.HTTPBadRequestError(err)
+	}
+
+	invitee, err := up.P.FindByPublicKeyDERBase64(r.Member)
+	if err != nil {
+		return httperror.New(http.StatusBadRequest, "member_public_key",
+			httperror.BadParam(fmt.Sprintf("unable to find member by public key: %v", err)),
+		)
+	}
+
+	um, err := cp.P.Invite(chann, *invitee)
+	if err == channel.ErrAlreadyMember {
+		return httperror.New(http.StatusBadRequest, "member_public_key", httperror.BadParam(err.Error()))
+	} else if err != nil {
+		return httperror.HTTPInternalServerError(fmt.Errorf("unable to invite user: %v", err))
+	}
+	event.H.Publish(event.New(event.InvitationCreated, chann.PublicID, um), invitee.ID)
+
+	return c.JSONPretty(http.StatusCreated, um, "    ")
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+
+	cp "github.com/krostar/nebulo-server/channel/provider"
+	"github.com/krostar/nebulo-server/event"
+)
+
+// ChanJoin handle the route PUT /chan/:chan/membership.
+// Accept an invitation to join a channel
+/**
+ * @api {put} /chan/:chan/membership Join a channel
+ * @apiDescription Accept a pending invitation, the user starts to receive the messages of the channel
+ * @apiName Channel - Join
+ * @apiGroup Channel
+ *
+ * @apiParam {String} chan public identifier of the channel
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X PUT -v --cert alice.crt --key alice.key "https://api.nebulo.io/chan/yDDvaSdT1hlUXq6nJ0q9Pg/membership"
+ *
+ * @apiSuccess (Success) {nothing} 200 OK
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 200 "OK"
+ *		{
+ *			"user": {...},
+ *			"channel": {...},
+ *			"invited": "2017-05-11T10:15:55Z",
+ *			"joined": "2017-05-11T10:16:02Z"
+ *		}
+ *
+ * @apiError (Errors 4XX) {json} 400 Bad request: channel already joined
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate
+ * @apiError (Errors 4XX) {json} 404 Not found: user or invitation not found
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func ChanJoin(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	um, err := getMembership(u, c.Param("chan"))
+	if err != nil {
+		return err
+	}
+	if !um.IsPending() {
+		return httperror.HTTPBadRequestError(errors.New("channel already joined"))
+	}
+
+	if err = cp.P.Join(um); err != nil {
+		return httperror.HTTPInternalServerError(fmt.Errorf("unable to join channel: %v", err))
+	}
+	um.Channel.Members = append(um.Channel.Members, *u)
+	event.H.Publish(event.New(event.MembershipJoined, um.Channel.PublicID, um), membersID(&um.Channel)...)
+
+	return c.JSONPretty(http.StatusOK, um, "    ")
+}
+
+// ChanLeave handle the route DELETE /chan/:chan/membership.
+// Leave a channel, or decline an invitation
+/**
+ * @api {delete} /chan/:chan/membership Leave a channel
+ * @apiDescription Leave the channel, or decline the invitation if the channel was not joined yet.
+ * The user stops receiving the messages of the channel.
+ * @apiName Channel - Leave
+ * @apiGroup Channel
+ *
+ * @apiParam {String} chan public identifier of the channel
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X DELETE -v --cert alice.crt --key alice.key "https://api.nebulo.io/chan/yDDvaSdT1hlUXq6nJ0q9Pg/membership"
+ *
+ * @apiSuccess (Success) {nothing} 204 No Content
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 204 "No Content"
+ *
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate
+ * @apiError (Errors 4XX) {json} 404 Not found: user, channel or invitation not found
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func ChanLeave(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	um, err := getMembership(u, c.Param("chan"))
+	if err != nil {
+		return err
+	}
+
+	if err = cp.P.Leave(um); err != nil {
+		return httperror.HTTPInternalServerError(fmt.Errorf("unable to leave channel: %v", err))
+	}
+	// the user is notified too, to sync his other devices
+	usersID := membersID(&um.Channel)
+	if um.IsPending() {
+		usersID = append(usersID, u.ID)
+	}
+	event.H.Publish(event.New(event.MembershipLeft, um.Channel.PublicID, um), usersID...)
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -3,12 +3,15 @@ package handler
 import (
 	"fmt"
 	"net/http"
+	"regexp"
+	"time"
 
 	"github.com/krostar/nebulo-golib/router/httperror"
 	"github.com/labstack/echo"
 
 	"github.com/krostar/nebulo-server/channel"
-	cp "github.com/krostar/nebulo-server/channel/provider"
+	"github.com/krostar/nebulo-server/event"
+	"github.com/krostar/nebulo-server/identifier"
 	"github.com/krostar/nebulo-server/message"
 	mp "github.com/krostar/nebulo-server/message/provider"
 	"github.com/krostar/nebulo-server/user"
@@ -22,10 +25,49 @@ type messageInfos struct {
 
 // ChanMessageCreateRequest store the request body for a ChanMessageCreate request
 type ChanMessageCreateRequest struct {
-	ChannelName string         `json:"channel_name"`
-	Messages    []messageInfos `json:"messages"`
+	ClientID string         `json:"client_id"`
+	TTL      int            `json:"ttl"`
+	Messages []messageInfos `json:"messages"`
 }
 
+type messageCreatedResponse struct {
+	ID string `json:"id"`
+}
+
+var clientIDRegexp = regexp.MustCompile("^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$")
+
+// ChanMessageCreate handle the route POST /chan/:chan/message.
+// Send a message to every members of a channel
+/**
+ * @api {post} /chan/:chan/message Send a message
+ * @apiDescription Send a message to every members who joined the channel, the sender included.
+ * Each member receive his own encrypted copy, a copy must be provided for every members, no more, no less.
+ * A client identifier (UUID) can be given to safely retry the request: if the sender already sent
+ * a message with this identifier, the message is not created again and the same response is returned.
+ * The message is deleted after the shortest of its ttl and the channel messages ttl.
+ * @apiName Message - Create
+ * @apiGroup Message
+ *
+ * @apiParam {String} chan public identifier of the channel
+ * @apiParam {String} [client_id] UUID generated by the client for this message
+ * @apiParam {Number} [ttl] number of seconds before the message is deleted
+ * @apiParam {Object[]} messages encrypted copy of the message for each member
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X POST -v --cert bob.crt --key bob.key "https://api.nebulo.io/chan/yDDvaSdT1hlUXq6nJ0q9Pg/message" --data "{\"client_id\": \"0b5c5dc1-5b62-4b7e-a7b4-d2a4e3c1c8f1\", \"messages\": [{\"receiver_pkey\": \"MIICIjANBgkqhkiG9w0BAQEFAAOCAg8AMIICCgKCAgEA...\", \"message\": {\"message\": \"...\", \"keys\": \"...\", \"integrity\": \"...\"}}]}"
+ *
+ * @apiSuccess (Success) {json} 201 Created
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 201 "Created"
+ *		{
+ *			"id": "oS3lBpGZS5SCe2VY9oIj4A"
+ *		}
+ *
+ * @apiError (Errors 4XX) {json} 400 Bad request: bad json input, invalid client id or ttl, or receivers are not exactly the channel members
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate
+ * @apiError (Errors 4XX) {json} 404 Not found: user or channel not found
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
 func ChanMessageCreate(c echo.Context) (err error) {
 	u, err := GetLoggedUser(c.Get("user"))
 	if err != nil {
@@ -38,24 +80,116 @@ func ChanMessageCreate(c echo.Context) (err error) {
 		return httperror.HTTPBadRequestError(err)
 	}
 
-	var (
-		receiver *user.User
-		chnel    *channel.Channel
-	)
-	chnel, err = cp.P.FindByName(*u, r.ChannelName)
+	if r.ClientID != "" && !clientIDRegexp.MatchString(r.ClientID) {
+		return httperror.New(http.StatusBadRequest, "client_id", httperror.BadParam("client_id must be an UUID"))
+	}
+	if r.TTL < 0 {
+		return httperror.New(http.StatusBadRequest, "ttl", httperror.BadParam("ttl can't be negative"))
+	}
+
+	chnel, err := getChannel(u, c.Param("chan"))
 	if err != nil {
-		return httperror.HTTPBadRequestError(fmt.Errorf("chan %q not found: %v", r.ChannelName, err))
+		return err
 	}
 
-	for _, m := range r.Messages {
+	// the message may already exists if the client is retrying a request
+	if r.ClientID != "" {
+		if sent, err := sentMessage(c, u, chnel, r.ClientID); sent || err != nil {
+			return err
+		}
+	}
+
+	receivers, err := findReceivers(chnel, r.Messages)
+	if err != nil {
+		return err
+	}
+
+	// every receivers copies share the same public identifier
+	publicID, err := identifier.New()
+	if err != nil {
+		return httperror.HTTPInternalServerError(fmt.Errorf("unable to generate message public id: %v", err))
+	}
+
+	msgs := make(map[int]message.SecureMsg)
+	usersByID := make(map[int]*user.User)
+	for i, m := range r.Messages {
+		msgs[receivers[i].ID] = m.Message
+		usersByID[receivers[i].ID] = receivers[i]
+	}
+
+	// every members must receive the message, otherwise nothing is created
+	messages, err := mp.P.Create(*u, *chnel, publicID, r.ClientID, time.Duration(r.TTL)*time.Second, msgs)
+	if err == message.ErrClientIDExists {
+		// a concurrent retry created the message meanwhile
+		if sent, err := sentMessage(c, u, chnel, r.ClientID); sent || err != nil {
+			return err
+		}
+		return messageError(message.ErrClientIDExists)
+	} else if err != nil {
+		return messageError(err)
+	}
+
+	// push the message to the receivers who are online
+	for _, msg := range messages {
+		msg.Channel = *chnel
+		msg.Sender = *u
+		msg.Receiver = *usersByID[msg.ReceiverID]
+		event.H.Publish(event.New(event.MessageCreated, chnel.PublicID, msg), msg.ReceiverID)
+	}
+
+	return c.JSONPretty(http.StatusCreated, messageCreatedResponse{ID: publicID}, "    ")
+}
+
+// sentMessage reply with the message previously created by the sender with the client identifier,
+// sent is false if there is no such message
+func sentMessage(c echo.Context, u *user.User, chnel *channel.Channel, clientID string) (sent bool, err error) {
+	messages, err := mp.P.FindByClientID(*u, clientID)
+	if err == message.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, httperror.HTTPInternalServerError(err)
+	}
+
+	if messages[0].ChannelID != chnel.ID {
+		return true, httperror.New(http.StatusBadRequest, "client_id", httperror.BadParam("client_id already used for another channel"))
+	}
+	return true, c.JSONPretty(http.StatusCreated, messageCreatedResponse{ID: messages[0].PublicID}, "    ")
+}
+
+// findReceivers return the receiver of each message, only members who joined the channel
+// can receive messages and each of them can receive only one copy
+func findReceivers(chnel *channel.Channel, infos []messageInfos) (receivers []*user.User, err error) {
+	members := make(map[int]bool)
+	for _, id := range membersID(chnel) {
+		members[id] = true
+	}
+
+	if receivers, err = resolveReceivers(infos); err != nil {
+		return nil, err
+	}
+	for _, receiver := range receivers {
+		if !members[receiver.ID] {
+			return nil, httperror.HTTPBadRequestError(fmt.Errorf("user %s is not a member of the channel", receiver.FingerPrint))
+		}
+	}
+	return receivers, nil
+}
+
+// resolveReceivers return the user identified by the public key of each message infos,
+// in the same order, a user can only receive one copy
+func resolveReceivers(infos []messageInfos) (receivers []*user.User, err error) {
+	var receiver *user.User
+	seen := make(map[int]bool)
+	for _, m := range infos {
 		receiver, err = up.P.FindByPublicKeyDERBase64(m.Receiver)
 		if err != nil {
-			return httperror.HTTPBadRequestError(fmt.Errorf("user not found: %v", err))
+			return nil, httperror.HTTPBadRequestError(fmt.Errorf("user not found: %v", err))
 		}
-		if _, err = mp.P.Create(*u, *receiver, *chnel, m.Message); err != nil {
-			return httperror.HTTPInternalServerError(fmt.Errorf("unable to create channel: %v", err))
+		if seen[receiver.ID] {
+			return nil, httperror.HTTPBadRequestError(fmt.Errorf("user %s receive more than one copy of the message", receiver.FingerPrint))
 		}
+		seen[receiver.ID] = true
+		receivers = append(receivers, receiver)
 	}
-
-	return c.JSONPretty(http.StatusCreated, nil, "    ")
+	return receivers, nil
 }
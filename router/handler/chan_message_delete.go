@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+
+	"github.com/krostar/nebulo-server/event"
+	mp "github.com/krostar/nebulo-server/message/provider"
+)
+
+// ChanMessageDelete handle the route DELETE /chan/:chan/message/:message.
+// Delete a message for each of its receivers
+/**
+ * @api {delete} /chan/:chan/message/:message Delete a message
+ * @apiDescription Delete a message for each of its receivers. By default the content of the message
+ * is wiped but the message is kept with a deletion date, to let clients know it has been deleted.
+ * With the "hard" parameter the message is completely removed.
+ * Only the sender of the message can delete it.
+ * @apiName Message - Delete
+ * @apiGroup Message
+ *
+ * @apiParam {String} chan public identifier of the channel
+ * @apiParam {String} message public identifier of the message
+ * @apiParam {Boolean} [hard=false] completely remove the message
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X DELETE -v --cert bob.crt --key bob.key "https://api.nebulo.io/chan/yDDvaSdT1hlUXq6nJ0q9Pg/message/oS3lBpGZS5SCe2VY9oIj4A?hard=true"
+ *
+ * @apiSuccess (Success) {nothing} 204 No Content
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 204 "No Content"
+ *
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate
+ * @apiError (Errors 4XX) {json} 404 Not found: user, channel or message not found
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func ChanMessageDelete(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	chnel, err := getChannel(u, c.Param("chan"))
+	if err != nil {
+		return err
+	}
+
+	hard := c.QueryParam("hard") == "true"
+	messages, err := mp.P.Delete(*u, *chnel, c.Param("message"), hard)
+	if err != nil {
+		return messageError(err)
+	}
+
+	for _, msg := range messages {
+		msg.Channel = *chnel
+		msg.Sender = *u
+		event.H.Publish(event.New(event.MessageDeleted, chnel.PublicID, msg), msg.ReceiverID)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+
+	"github.com/krostar/nebulo-server/event"
+	"github.com/krostar/nebulo-server/message"
+	mp "github.com/krostar/nebulo-server/message/provider"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// ChanMessageEditRequest store the request body for a ChanMessageEdit request
+type ChanMessageEditRequest struct {
+	Messages []messageInfos `json:"messages"`
+}
+
+// ChanMessageEdit handle the route PUT /chan/:chan/message/:message.
+// Replace the content of a message for each of its receivers
+/**
+ * @api {put} /chan/:chan/message/:message Edit a message
+ * @apiDescription Replace the encrypted content of a message. Since each receiver has his own encrypted copy,
+ * a new content must be provided for every receivers of the original message, no more, no less,
+ * including the ones who left the channel since.
+ * Only the sender of the message can edit it.
+ * @apiName Message - Edit
+ * @apiGroup Message
+ *
+ * @apiParam {String} chan public identifier of the channel
+ * @apiParam {String} message public identifier of the message
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X PUT -v --cert bob.crt --key bob.key "https://api.nebulo.io/chan/yDDvaSdT1hlUXq6nJ0q9Pg/message/oS3lBpGZS5SCe2VY9oIj4A" --data "{\"messages\": [{\"receiver_pkey\": \"MIICIjANBgkqhkiG9w0BAQEFAAOCAg8AMIICCgKCAgEA...\", \"message\": {\"message\": \"...\", \"keys\": \"...\", \"integrity\": \"...\"}}]}"
+ *
+ * @apiSuccess (Success) {nothing} 204 No Content
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 204 "No Content"
+ *
+ * @apiError (Errors 4XX) {json} 400 Bad request: bad json input or receivers mismatch
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate
+ * @apiError (Errors 4XX) {json} 404 Not found: user, channel or message not found
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func ChanMessageEdit(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	// bind the request body to the struct
+	r := ChanMessageEditRequest{}
+	if err = c.Bind(&r); err != nil {
+		return httperror.HTTPBadRequestError(err)
+	}
+
+	chnel, err := getChannel(u, c.Param("chan"))
+	if err != nil {
+		return err
+	}
+
+	// the receivers are the ones of the original message, even if they left the channel since
+	receivers, err := resolveReceivers(r.Messages)
+	if err != nil {
+		return err
+	}
+	msgs := make(map[int]message.SecureMsg)
+	usersByID := make(map[int]*user.User)
+	for i, m := range r.Messages {
+		msgs[receivers[i].ID] = m.Message
+		usersByID[receivers[i].ID] = receivers[i]
+	}
+
+	messages, err := mp.P.Edit(*u, *chnel, c.Param("message"), msgs)
+	if err != nil {
+		return messageError(err)
+	}
+
+	for _, msg := range messages {
+		msg.Channel = *chnel
+		msg.Sender = *u
+		msg.Receiver = *usersByID[msg.ReceiverID]
+		event.H.Publish(event.New(event.MessageEdited, chnel.PublicID, msg), msg.ReceiverID)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
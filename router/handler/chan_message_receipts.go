@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+
+	mp "github.com/krostar/nebulo-server/message/provider"
+)
+
+// ChanMessageReceipts handle the route GET /chan/:chan/message/:message/receipts.
+// Return the delivery and read state of a message for each of its receivers
+/**
+ * @api {get} /chan/:chan/message/:message/receipts Get message receipts
+ * @apiDescription Return, for each receiver of a message, when the message has been delivered
+ * (fetched by the receiver) and seen. Only the sender of the message can get its receipts.
+ * @apiName Message - Receipts
+ * @apiGroup Message
+ *
+ * @apiParam {String} chan public identifier of the channel
+ * @apiParam {String} message public identifier of the message
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X GET -v --cert bob.crt --key bob.key "https://api.nebulo.io/chan/yDDvaSdT1hlUXq6nJ0q9Pg/message/oS3lBpGZS5SCe2VY9oIj4A/receipts"
+ *
+ * @apiSuccess (Success) {json} 200 OK
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 200 "OK"
+ *		[
+ *			{
+ *				"id": "oS3lBpGZS5SCe2VY9oIj4A",
+ *				"receiver_fingerprint": "...",
+ *				"delivered": "2017-03-22T12:00:00Z",
+ *				"seen": null
+ *			}
+ *		]
+ *
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate
+ * @apiError (Errors 4XX) {json} 404 Not found: user, channel or message not found
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func ChanMessageReceipts(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	chnel, err := getChannel(u, c.Param("chan"))
+	if err != nil {
+		return err
+	}
+
+	receipts, err := mp.P.Receipts(*u, *chnel, c.Param("message"))
+	if err != nil {
+		return messageError(err)
+	}
+
+	return c.JSONPretty(http.StatusOK, receipts, "    ")
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+
+	"github.com/krostar/nebulo-server/config"
+	"github.com/krostar/nebulo-server/event"
+	"github.com/krostar/nebulo-server/message"
+	mp "github.com/krostar/nebulo-server/message/provider"
+)
+
+// ChanMessagesAckRequest store the request body for a ChanMessagesAck request
+type ChanMessagesAckRequest struct {
+	Messages []string `json:"messages"`
+}
+
+// ChanMessagesAck handle the route PUT /chan/:chan/messages/ack.
+// Delete the logged user copies of the messages he received
+/**
+ * @api {put} /chan/:chan/messages/ack Acknowledge messages
+ * @apiDescription Acknowledge the reception of messages, the user copies of the messages are deleted from the server.
+ * The senders of the messages are notified in real-time with a "message_acknowledged" event.
+ * Only available if the server runs with the delete after delivery mode.
+ * @apiName Messages - Acknowledge
+ * @apiGroup Message
+ *
+ * @apiParam {String} chan public identifier of the channel
+ * @apiParam {String[]} messages public identifiers of the received messages
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X PUT -v --cert bob.crt --key bob.key "https://api.nebulo.io/chan/yDDvaSdT1hlUXq6nJ0q9Pg/messages/ack" --data "{\"messages\": [\"oS3lBpGZS5SCe2VY9oIj4A\"]}"
+ *
+ * @apiSuccess (Success) {nothing} 204 No Content
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 204 "No Content"
+ *
+ * @apiError (Errors 4XX) {json} 400 Bad request: bad json input, no messages or delete after delivery mode disabled
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate
+ * @apiError (Errors 4XX) {json} 404 Not found: user or channel not found
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func ChanMessagesAck(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	if !config.Config.Run.Messages.DeleteAfterDelivery {
+		return httperror.HTTPBadRequestError(errors.New("messages acknowledgement is disabled on this server"))
+	}
+
+	// bind the request body to the struct
+	r := ChanMessagesAckRequest{}
+	if err = c.Bind(&r); err != nil {
+		return httperror.HTTPBadRequestError(err)
+	}
+	if len(r.Messages) == 0 {
+		return httperror.New(http.StatusBadRequest, "messages", httperror.BadParam("at least one message is required"))
+	}
+
+	chnel, err := getChannel(u, c.Param("chan"))
+	if err != nil {
+		return err
+	}
+
+	messages, err := mp.P.Acknowledge(*u, *chnel, r.Messages)
+	if err != nil {
+		return messageError(err)
+	}
+
+	// notify the senders their messages have been delivered
+	for _, msg := range messages {
+		receipt := &message.Receipt{
+			Message:   msg.PublicID,
+			Receiver:  u.FingerPrint,
+			Delivered: msg.Delivered,
+			Seen:      msg.Seen,
+		}
+		event.H.Publish(event.New(event.MessageAcknowledged, chnel.PublicID, receipt), msg.SenderID)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
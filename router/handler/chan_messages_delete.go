@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+
+	"github.com/krostar/nebulo-server/message"
+	mp "github.com/krostar/nebulo-server/message/provider"
+)
+
+type messagesDeletedResponse struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// ChanMessagesDelete handle the route DELETE /chan/:chan/messages.
+// Delete the logged user copies of a range of messages
+/**
+ * @api {delete} /chan/:chan/messages Delete a range of messages
+ * @apiDescription Delete the copies of the messages received by the user in a channel, by date range and/or by messages range.
+ * Messages are only deleted for the user, other receivers keep their own copies. At least one bound is required,
+ * every bounds are inclusive.
+ * @apiName Messages - Delete range
+ * @apiGroup Message
+ *
+ * @apiParam {String} chan public identifier of the channel
+ * @apiParam {String} [from] RFC3339 date of the oldest message to delete
+ * @apiParam {String} [to] RFC3339 date of the newest message to delete
+ * @apiParam {String} [from_id] public identifier of the oldest message to delete
+ * @apiParam {String} [to_id] public identifier of the newest message to delete
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X DELETE -v --cert bob.crt --key bob.key "https://api.nebulo.io/chan/yDDvaSdT1hlUXq6nJ0q9Pg/messages?to=2017-03-22T12:00:00Z"
+ *
+ * @apiSuccess (Success) {json} 200 OK
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 200 "OK"
+ *		{
+ *			"deleted": 42
+ *		}
+ *
+ * @apiError (Errors 4XX) {json} 400 Bad request: unable to parse a bound or no bounds given
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate
+ * @apiError (Errors 4XX) {json} 404 Not found: user or channel not found
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func ChanMessagesDelete(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	queryParams := c.QueryParams()
+	r := message.Range{
+		FromID: queryParams.Get("from_id"),
+		ToID:   queryParams.Get("to_id"),
+	}
+	if from := queryParams.Get("from"); from != "" {
+		if r.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return httperror.HTTPBadRequestError(fmt.Errorf("unable to parse from %q: %v", from, err))
+		}
+	}
+	if to := queryParams.Get("to"); to != "" {
+		if r.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return httperror.HTTPBadRequestError(fmt.Errorf("unable to parse to %q: %v", to, err))
+		}
+	}
+	if r.IsEmpty() {
+		return httperror.HTTPBadRequestError(fmt.Errorf("at least one of from, to, from_id or to_id is required"))
+	}
+
+	chann, err := getChannel(u, c.Param("chan"))
+	if err != nil {
+		return err
+	}
+	deleted, err := mp.P.DeleteRange(*u, *chann, r)
+	if err != nil {
+		return httperror.HTTPInternalServerError(err)
+	}
+
+	return c.JSONPretty(http.StatusOK, messagesDeletedResponse{Deleted: deleted}, "    ")
+}
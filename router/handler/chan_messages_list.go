@@ -1,51 +1,136 @@
 package handler
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/krostar/nebulo-golib/router/httperror"
 	"github.com/labstack/echo"
 
-	"github.com/krostar/nebulo-server/channel"
-	cp "github.com/krostar/nebulo-server/channel/provider"
+	"github.com/krostar/nebulo-server/message"
 	mp "github.com/krostar/nebulo-server/message/provider"
 )
 
+// messagesListMaxLimit is the default and maximum number of messages of a page
+const messagesListMaxLimit = 50
+
+type messagesListResponse struct {
+	// Messages is either a list of *message.Message or of *message.Compact
+	Messages interface{} `json:"messages"`
+	HasMore  bool        `json:"has_more"`
+	// Before and After are the cursors to use to get the previous and next pages
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// ChanMessagesList handle the route GET /chan/:chan/messages.
+// Return a page of the messages received by the logged user in a channel
+/**
+ * @api {get} /chan/:chan/messages Get messages list
+ * @apiDescription Return a page of the messages received by the user in a channel, ordered from the oldest to the newest.
+ * Without cursor the last messages of the channel are returned. Cursors are opaque strings found in the
+ * before and after fields of a previous response, has_more is true if more messages exist in the requested direction.
+ * Messages embed their channel, with its members and creator, and their sender. With compact set to true,
+ * messages reference their channel by its public identifier and their sender by its key fingerprint instead.
+ * @apiName Messages - List
+ * @apiGroup Message
+ *
+ * @apiParam {String} chan public identifier of the channel
+ * @apiParam {String} [before] return the messages before this cursor
+ * @apiParam {String} [after] return the messages after this cursor
+ * @apiParam {Number{1-50}} [limit=50] maximum number of messages to return
+ * @apiParam {Boolean} [compact=false] return the compact representation of the messages
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X GET -v --cert bob.crt --key bob.key "https://api.nebulo.io/chan/yDDvaSdT1hlUXq6nJ0q9Pg/messages?before=NDI&limit=20"
+ *
+ * @apiSuccess (Success) {json} 200 OK
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 200 "OK"
+ *		{
+ *			"messages": [...],
+ *			"has_more": true,
+ *			"before": "MjI",
+ *			"after": "NDE"
+ *		}
+ *
+ * @apiError (Errors 4XX) {json} 400 Bad request: unable to parse limit, compact or cursors
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate
+ * @apiError (Errors 4XX) {json} 404 Not found: user or channel not found
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
 func ChanMessagesList(c echo.Context) (err error) {
 	u, err := GetLoggedUser(c.Get("user"))
 	if err != nil {
 		return httperror.UserNotFound()
 	}
 
-	channelParam := c.Param("chan")
-
 	queryParams := c.QueryParams()
-	limit, err := strconv.Atoi(queryParams.Get("limit"))
-	if err != nil {
-		return httperror.HTTPBadRequestError(fmt.Errorf("unable to parse limit %q: %v", queryParams.Get("limit"), err))
+	page := message.Page{Limit: messagesListMaxLimit}
+	if limit := queryParams.Get("limit"); limit != "" {
+		if page.Limit, err = strconv.Atoi(limit); err != nil || page.Limit <= 0 {
+			return httperror.HTTPBadRequestError(fmt.Errorf("unable to parse limit %q: must be a positive number", limit))
+		}
 	}
-	lastRead, err := time.Parse(time.RFC3339, queryParams.Get("last_read"))
-	if err != nil {
-		return httperror.HTTPBadRequestError(fmt.Errorf("unable to find last read %q: %v", queryParams.Get("last_read"), err))
+	if page.Limit > messagesListMaxLimit {
+		page.Limit = messagesListMaxLimit
 	}
-
-	if limit < -50 {
-		limit = -50
-	} else if limit > 50 {
-		limit = 50
+	if page.Before, err = decodeCursor(queryParams.Get("before")); err != nil {
+		return httperror.HTTPBadRequestError(fmt.Errorf("unable to parse before cursor: %v", err))
+	}
+	if page.After, err = decodeCursor(queryParams.Get("after")); err != nil {
+		return httperror.HTTPBadRequestError(fmt.Errorf("unable to parse after cursor: %v", err))
+	}
+	compact := false
+	if value := queryParams.Get("compact"); value != "" {
+		if compact, err = strconv.ParseBool(value); err != nil {
+			return httperror.HTTPBadRequestError(fmt.Errorf("unable to parse compact %q: must be a boolean", value))
+		}
 	}
 
-	chann, err := cp.P.Find(channel.Channel{Name: channelParam})
+	chann, err := getChannel(u, c.Param("chan"))
 	if err != nil {
-		return httperror.HTTPBadRequestError(fmt.Errorf("unable to find channel %q: %v", channelParam, err))
+		return err
 	}
-	list, err := mp.P.List(*u, *chann, lastRead, limit)
+	list, hasMore, err := mp.P.List(*u, *chann, page)
 	if err != nil {
 		return httperror.HTTPInternalServerError(err)
 	}
 
-	return c.JSONPretty(http.StatusOK, list, "    ")
+	response := messagesListResponse{Messages: list, HasMore: hasMore}
+	if compact {
+		compacts := make([]*message.Compact, 0, len(list))
+		for _, m := range list {
+			compacts = append(compacts, m.Compact())
+		}
+		response.Messages = compacts
+	}
+	if len(list) > 0 {
+		response.Before = encodeCursor(list[0].Sequence)
+		response.After = encodeCursor(list[len(list)-1].Sequence)
+	}
+
+	return c.JSONPretty(http.StatusOK, response, "    ")
+}
+
+// encodeCursor hide the message sequence number to let clients only use it as an opaque cursor
+func encodeCursor(sequence int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(sequence, 10)))
+}
+
+func decodeCursor(cursor string) (sequence int64, err error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.New("malformed cursor")
+	}
+	if sequence, err = strconv.ParseInt(string(raw), 10, 64); err != nil || sequence <= 0 {
+		return 0, errors.New("malformed cursor")
+	}
+	return sequence, nil
 }
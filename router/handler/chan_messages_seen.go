@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+
+	"github.com/krostar/nebulo-server/event"
+	"github.com/krostar/nebulo-server/message"
+	mp "github.com/krostar/nebulo-server/message/provider"
+)
+
+// ChanMessagesSeenRequest store the request body for a ChanMessagesSeen request,
+// only one of Messages and UpTo can be set
+type ChanMessagesSeenRequest struct {
+	Messages []string `json:"messages"`
+	UpTo     string   `json:"up_to"`
+}
+
+// ChanMessagesSeen handle the route PUT /chan/:chan/messages/seen.
+// Mark messages received by the logged user as seen
+/**
+ * @api {put} /chan/:chan/messages/seen Mark messages as seen
+ * @apiDescription Mark the given messages, or every messages up to the given one included, as seen by the user.
+ * The senders of the messages are notified in real-time with a "message_seen" event.
+ * @apiName Messages - Seen
+ * @apiGroup Message
+ *
+ * @apiParam {String} chan public identifier of the channel
+ * @apiParam {String[]} [messages] public identifiers of the seen messages
+ * @apiParam {String} [up_to] public identifier of the last seen message
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X PUT -v --cert bob.crt --key bob.key "https://api.nebulo.io/chan/yDDvaSdT1hlUXq6nJ0q9Pg/messages/seen" --data "{\"up_to\": \"oS3lBpGZS5SCe2VY9oIj4A\"}"
+ *
+ * @apiSuccess (Success) {nothing} 204 No Content
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 204 "No Content"
+ *
+ * @apiError (Errors 4XX) {json} 400 Bad request: bad json input, or not exactly one of messages and up_to
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate
+ * @apiError (Errors 4XX) {json} 404 Not found: user, channel or up_to message not found
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func ChanMessagesSeen(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	// bind the request body to the struct
+	r := ChanMessagesSeenRequest{}
+	if err = c.Bind(&r); err != nil {
+		return httperror.HTTPBadRequestError(err)
+	}
+	if (len(r.Messages) == 0) == (r.UpTo == "") {
+		return httperror.HTTPBadRequestError(fmt.Errorf("exactly one of messages and up_to is required"))
+	}
+
+	chnel, err := getChannel(u, c.Param("chan"))
+	if err != nil {
+		return err
+	}
+
+	var messages []*message.Message
+	if r.UpTo != "" {
+		messages, err = mp.P.MarkSeenUpTo(*u, *chnel, r.UpTo)
+	} else {
+		messages, err = mp.P.MarkSeen(*u, *chnel, r.Messages)
+	}
+	if err != nil {
+		return messageError(err)
+	}
+
+	// notify the senders their messages have been read
+	for _, msg := range messages {
+		receipt := &message.Receipt{
+			Message:   msg.PublicID,
+			Receiver:  u.FingerPrint,
+			Delivered: msg.Delivered,
+			Seen:      msg.Seen,
+		}
+		event.H.Publish(event.New(event.MessageSeen, chnel.PublicID, receipt), msg.SenderID)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net/http"
+
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+
+	"github.com/krostar/nebulo-server/certificate"
+	certp "github.com/krostar/nebulo-server/certificate/provider"
+)
+
+// CRL handle the route GET /crl.
+// Return the certificate revocation list of the clients CA, DER encoded
+/**
+ * @api {get} /crl Get the certificate revocation list
+ * @apiDescription Return the list of the revoked clients certificates, signed by the clients CA and DER encoded.
+ * The list is regenerated periodically, the Expires header is set to its next update date.
+ * @apiName CRL - DER
+ * @apiGroup Certificate
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X GET --cacert ca.crt -v "https://api.nebulo.io/crl" -o nebulo.crl
+ *
+ * @apiSuccess (Success) {binary} 200 OK
+ *
+ * @apiError (Errors 4XX) {json} 404 Not found: no revocation list generated yet
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func CRL(c echo.Context) (err error) {
+	crl, err := getCRL(c)
+	if err != nil {
+		return err
+	}
+	return c.Blob(http.StatusOK, "application/pkix-crl", crl.DER)
+}
+
+// CRLPEM handle the route GET /crl.pem.
+// Return the certificate revocation list of the clients CA, PEM encoded
+/**
+ * @api {get} /crl.pem Get the PEM certificate revocation list
+ * @apiDescription Return the list of the revoked clients certificates, signed by the clients CA and PEM encoded.
+ * The list is regenerated periodically, the Expires header is set to its next update date.
+ * @apiName CRL - PEM
+ * @apiGroup Certificate
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X GET --cacert ca.crt -v "https://api.nebulo.io/crl.pem"
+ *
+ * @apiSuccess (Success) {binary} 200 OK
+ * @apiSuccessExample {binary} Success example
+ *		HTTP/1.1 200 "OK"
+ *		-----BEGIN X509 CRL-----
+		MIIBYDCBygIBATANBgkqhkiG9w0BAQsFADARMQ8wDQYDVQQDDAZuZWJ1bG8XDTE3
+		...
+		-----END X509 CRL-----
+ *
+ * @apiError (Errors 4XX) {json} 404 Not found: no revocation list generated yet
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+*/
+func CRLPEM(c echo.Context) (err error) {
+	crl, err := getCRL(c)
+	if err != nil {
+		return err
+	}
+	return c.Blob(http.StatusOK, "application/x-pem-file", pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: crl.DER}))
+}
+
+// getCRL return the last certificate revocation list and let clients cache it until its next update
+func getCRL(c echo.Context) (crl *certificate.CRL, err error) {
+	crl, err = certp.P.LastCRL()
+	if err == certificate.ErrCRLNotFound {
+		return nil, httperror.HTTPNotFoundError(err)
+	} else if err != nil {
+		return nil, httperror.HTTPInternalServerError(fmt.Errorf("unable to get crl: %v", err))
+	}
+
+	c.Response().Header().Set("Last-Modified", crl.ThisUpdate.UTC().Format(http.TimeFormat))
+	c.Response().Header().Set("Expires", crl.NextUpdate.UTC().Format(http.TimeFormat))
+	return crl, nil
+}
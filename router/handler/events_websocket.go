@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/krostar/nebulo-golib/log"
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+
+	cp "github.com/krostar/nebulo-server/channel/provider"
+	"github.com/krostar/nebulo-server/event"
+	"github.com/krostar/nebulo-server/user"
+)
+
+const (
+	// time allowed to write a message to the client
+	wsWriteWait = 10 * time.Second
+	// time allowed to read the next pong message from the client
+	wsPongWait = 60 * time.Second
+	// send pings to client with this period, must be less than wsPongWait
+	wsPingPeriod = (wsPongWait * 9) / 10
+	// maximum message size allowed from client
+	wsMaxMessageSize = 4096
+	// number of events kept for a slow client before disconnecting it
+	wsEventsBuffer = 64
+
+	// wsAllChannels can be used to subscribe to every channels at once
+	wsAllChannels = "*"
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// EventsWebSocketRequest store a message sent by the client through the websocket
+type EventsWebSocketRequest struct {
+	Action   string   `json:"action"`
+	Channels []string `json:"channels"`
+}
+
+type eventsWebSocketResponse struct {
+	Action   string   `json:"action"`
+	Channels []string `json:"channels,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+type wsConnection struct {
+	conn     *websocket.Conn
+	user     *user.User
+	channels map[string]bool
+	requests chan EventsWebSocketRequest
+	done     chan struct{} // closed when the read loop ends
+	stop     chan struct{} // closed when the write loop ends
+}
+
+// EventsWebSocket handle the route GET /ws.
+// Upgrade the connection to a websocket and push events to the logged user
+/**
+ * @api {get} /ws Real-time events
+ * @apiDescription Upgrade the connection to a websocket used to push events (like new messages) to the user.
+ * The client choose which channels it want to receive events from by sending
+ * {"action": "subscribe", "channels": ["id"]} or {"action": "unsubscribe", "channels": ["id"]},
+ * the special channel "*" match every channels. The server send a ping every 54 seconds and close
+ * the connection if the pong is not received within 60 seconds, or if the client is too slow to consume events.
+ * @apiName Events - WebSocket
+ * @apiGroup Events
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X GET -v --cert bob.crt --key bob.key -H "Connection: Upgrade" -H "Upgrade: websocket" "https://api.nebulo.io/ws"
+ *
+ * @apiSuccess (Success) {nothing} 101 Switching Protocols
+ * @apiSuccessExample {json} Event example
+ *		{
+ *			"id": 42,
+ *			"type": "message_created",
+ *			"channel": "yDDvaSdT1hlUXq6nJ0q9Pg",
+ *			"data": {
+ *				"message": "...",
+ *				"keys": "...",
+ *				"integrity": "..."
+ *			}
+ *		}
+ *
+ * @apiError (Errors 4XX) {json} 400 Bad request: not a websocket handshake
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate
+ * @apiError (Errors 4XX) {json} 404 Not found: user not found
+ */
+func EventsWebSocket(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	// on failure, the upgrader already replied to the client
+	conn, err := wsUpgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		return fmt.Errorf("unable to upgrade connection to websocket: %v", err)
+	}
+
+	ws := &wsConnection{
+		conn:     conn,
+		user:     u,
+		channels: make(map[string]bool),
+		requests: make(chan EventsWebSocketRequest),
+		done:     make(chan struct{}),
+		stop:     make(chan struct{}),
+	}
+
+	sub := event.H.Subscribe(u.ID, wsEventsBuffer)
+	defer event.H.Unsubscribe(sub)
+
+	go ws.readLoop()
+	ws.writeLoop(sub)
+	return nil
+}
+
+// readLoop is the only reader of the connection, it forward clients requests to the write loop
+func (ws *wsConnection) readLoop() {
+	defer close(ws.done)
+
+	ws.conn.SetReadLimit(wsMaxMessageSize)
+	if err := ws.conn.SetReadDeadline(time.Now().Add(wsPongWait)); err != nil {
+		return
+	}
+	ws.conn.SetPongHandler(func(string) error {
+		return ws.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var r EventsWebSocketRequest
+		if err := ws.conn.ReadJSON(&r); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Warningf("websocket of user %d closed unexpectedly: %v", ws.user.ID, err)
+			}
+			return
+		}
+		select {
+		case ws.requests <- r:
+		case <-ws.stop:
+			return
+		}
+	}
+}
+
+// writeLoop is the only writer of the connection, it send events, replies and pings
+func (ws *wsConnection) writeLoop(sub *event.Subscription) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer func() {
+		ticker.Stop()
+		close(ws.stop)
+		if err := ws.conn.Close(); err != nil {
+			log.Warningf("unable to close websocket of user %d: %v", ws.user.ID, err)
+		}
+	}()
+
+	for {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				ws.close(websocket.ClosePolicyViolation, sub.Err())
+				return
+			}
+			if !ws.channels[wsAllChannels] && !ws.channels[e.Channel] {
+				continue
+			}
+			if err := ws.write(e); err != nil {
+				return
+			}
+		case r := <-ws.requests:
+			if err := ws.write(ws.handleRequest(r)); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := ws.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		case <-ws.done:
+			return
+		}
+	}
+}
+
+func (ws *wsConnection) handleRequest(r EventsWebSocketRequest) *eventsWebSocketResponse {
+	response := &eventsWebSocketResponse{Action: r.Action, Channels: r.Channels}
+
+	switch r.Action {
+	case "subscribe":
+		for _, id := range r.Channels {
+			if id != wsAllChannels {
+				if _, err := cp.P.FindByPublicID(*ws.user, id); err != nil {
+					response.Error = fmt.Sprintf("unable to find channel %q: %v", id, err)
+					return response
+				}
+			}
+		}
+		for _, id := range r.Channels {
+			ws.channels[id] = true
+		}
+	case "unsubscribe":
+		for _, id := range r.Channels {
+			delete(ws.channels, id)
+		}
+	default:
+		response.Error = fmt.Sprintf("unknown action %q", r.Action)
+	}
+	return response
+}
+
+func (ws *wsConnection) write(v interface{}) (err error) {
+	if err = ws.conn.SetWriteDeadline(time.Now().Add(wsWriteWait)); err != nil {
+		return err
+	}
+	return ws.conn.WriteJSON(v)
+}
+
+func (ws *wsConnection) close(code int, reason error) {
+	var text string
+	if reason != nil {
+		text = reason.Error()
+	}
+	if err := ws.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(wsWriteWait)); err != nil {
+		log.Warningf("unable to send close message to user %d: %v", ws.user.ID, err)
+	}
+}
@@ -2,7 +2,16 @@ package handler
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
 
+	"github.com/krostar/nebulo-golib/router/httperror"
+
+	"github.com/krostar/nebulo-server/attachment"
+	ap "github.com/krostar/nebulo-server/attachment/provider"
+	"github.com/krostar/nebulo-server/channel"
+	cp "github.com/krostar/nebulo-server/channel/provider"
+	"github.com/krostar/nebulo-server/message"
 	"github.com/krostar/nebulo-server/user"
 )
 
@@ -19,3 +28,56 @@ func GetLoggedUser(loggedUser interface{}) (u *user.User, err error) {
 	}
 	return u, nil
 }
+
+// getChannel return the channel with the given public identifier if the user is one of its members
+func getChannel(u *user.User, publicID string) (c *channel.Channel, err error) {
+	c, err = cp.P.FindByPublicID(*u, publicID)
+	if err == channel.ErrNotFound {
+		return nil, httperror.HTTPNotFoundError(fmt.Errorf("channel %q not found", publicID))
+	} else if err != nil {
+		return nil, httperror.HTTPInternalServerError(fmt.Errorf("unable to find channel %q: %v", publicID, err))
+	}
+	return c, nil
+}
+
+// getMembership return the membership, pending or not, of the user in the channel with the given public identifier
+func getMembership(u *user.User, publicID string) (um *channel.UserMembership, err error) {
+	um, err = cp.P.FindMembership(*u, publicID)
+	if err == channel.ErrMembershipNotFound {
+		return nil, httperror.HTTPNotFoundError(fmt.Errorf("no invitation or membership found for channel %q", publicID))
+	} else if err != nil {
+		return nil, httperror.HTTPInternalServerError(fmt.Errorf("unable to find membership: %v", err))
+	}
+	return um, nil
+}
+
+// getAttachment return the attachment of the channel with the given public identifier
+func getAttachment(c *channel.Channel, publicID string) (a *attachment.Attachment, err error) {
+	a, err = ap.P.FindByPublicID(*c, publicID)
+	if err == attachment.ErrNotFound {
+		return nil, httperror.HTTPNotFoundError(fmt.Errorf("attachment %q not found", publicID))
+	} else if err != nil {
+		return nil, httperror.HTTPInternalServerError(fmt.Errorf("unable to find attachment %q: %v", publicID, err))
+	}
+	return a, nil
+}
+
+// membersID return the ID of every members of the channel
+func membersID(c *channel.Channel) (ids []int) {
+	for _, m := range c.Members {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
+
+// messageError convert a message provider error to the matching http error
+func messageError(err error) error {
+	switch err {
+	case message.ErrNotFound:
+		return httperror.HTTPNotFoundError(err)
+	case message.ErrReceiversMismatch:
+		return httperror.New(http.StatusBadRequest, "messages", httperror.BadParam(err.Error()))
+	default:
+		return httperror.HTTPInternalServerError(err)
+	}
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/krostar/nebulo-golib/log"
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/krostar/nebulo-server/certificate/responder"
+)
+
+// ocspRequestMaxSize is the maximum number of bytes of an OCSP request
+const ocspRequestMaxSize = 10 << 10
+
+var errOCSPDisabled = errors.New("ocsp responder is disabled")
+
+// OCSPPost handle the route POST /ocsp.
+// Return the status of a client certificate
+/**
+ * @api {post} /ocsp Get a certificate status
+ * @apiDescription OCSP responder (RFC 6960) for the certificates issued by the clients CA. The response is signed by
+ * a delegated responder and valid until its next update date; OCSP errors are sent with a 200 status code.
+ * @apiName OCSP - Post
+ * @apiGroup Certificate
+ *
+ * @apiExample {curl} Usage example
+ *		$>openssl ocsp -issuer ca.crt -cert bob.crt -url "https://api.nebulo.io/ocsp" -CAfile ca.crt
+ *
+ * @apiSuccess (Success) {binary} 200 OK
+ *
+ * @apiError (Errors 4XX) {json} 404 Not found: ocsp responder is disabled
+ * @apiError (Errors 4XX) {json} 413 Request entity too large: request is too large
+ */
+func OCSPPost(c echo.Context) (err error) {
+	rawRequest, err := ioutil.ReadAll(http.MaxBytesReader(c.Response().Writer, c.Request().Body, ocspRequestMaxSize))
+	if err != nil {
+		return httperror.HTTPRequestEntityTooLargeError(fmt.Errorf("unable to read ocsp request: %v", err))
+	}
+	return respondOCSP(c, rawRequest)
+}
+
+// OCSPGet handle the route GET /ocsp/:request.
+// Return the status of a client certificate
+/**
+ * @api {get} /ocsp/:request Get a certificate status
+ * @apiDescription OCSP responder (RFC 6960) for the certificates issued by the clients CA, the request is the url
+ * encoding of the base64 encoding of the DER encoded OCSP request. Responses can be cached by HTTP proxies until their
+ * next update date.
+ * @apiName OCSP - Get
+ * @apiGroup Certificate
+ *
+ * @apiParam {String} request url and base64 encoded OCSP request
+ *
+ * @apiSuccess (Success) {binary} 200 OK
+ *
+ * @apiError (Errors 4XX) {json} 404 Not found: ocsp responder is disabled
+ */
+func OCSPGet(c echo.Context) (err error) {
+	encoded, err := url.PathUnescape(c.Param("*"))
+	if err != nil {
+		return writeOCSP(c, ocsp.MalformedRequestErrorResponse)
+	}
+	rawRequest, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return writeOCSP(c, ocsp.MalformedRequestErrorResponse)
+	}
+	return respondOCSP(c, rawRequest)
+}
+
+func respondOCSP(c echo.Context, rawRequest []byte) (err error) {
+	if responder.R == nil {
+		return httperror.HTTPNotFoundError(errOCSPDisabled)
+	}
+
+	now := time.Now()
+	resp, err := responder.R.Respond(rawRequest, now)
+	switch {
+	case err == responder.ErrUnknownIssuer:
+		return writeOCSP(c, ocsp.UnauthorizedErrorResponse)
+	case err == responder.ErrMalformedRequest:
+		return writeOCSP(c, ocsp.MalformedRequestErrorResponse)
+	case err != nil:
+		log.Errorf("unable to answer ocsp request: %v", err)
+		return writeOCSP(c, ocsp.InternalErrorErrorResponse)
+	}
+
+	header := c.Response().Header()
+	header.Set("Last-Modified", resp.ThisUpdate.Format(http.TimeFormat))
+	header.Set("Expires", resp.NextUpdate.Format(http.TimeFormat))
+	if maxAge := int(resp.NextUpdate.Sub(now).Seconds()); maxAge > 0 {
+		header.Set("Cache-Control", "max-age="+strconv.Itoa(maxAge)+", public, no-transform, must-revalidate")
+	}
+	return writeOCSP(c, resp.DER)
+}
+
+func writeOCSP(c echo.Context, der []byte) error {
+	return c.Blob(http.StatusOK, "application/ocsp-response", der)
+}
@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/labstack/echo"
+
+	"github.com/krostar/nebulo-server/certificate"
+	certp "github.com/krostar/nebulo-server/certificate/provider"
+	"github.com/krostar/nebulo-server/certificate/responder"
+	"github.com/krostar/nebulo-server/user"
+)
+
+// headerKeySignature is the signature of the certificate request by the current key of the user,
+// it is required to renew the certificate with a new key
+const headerKeySignature = "Key-Signature"
+
+var errKeySignature = errors.New("signature of the certificate request by the current key does not match")
+
+// UserCertificateRenew handle the route POST /user/certificate.
+// Return a new CRT generated from the CRS submitted and the CA
+/**
+ * @api {post} /user/certificate Renew the certificate
+ * @apiDescription Issue a new certificate to the logged user, before its current certificate expires.
+ * The certificate request is either for the current key of the user, or for a new key; in the latter case
+ * the DER encoded certificate request must be signed with the current key (SHA-256, PKCS#1 v1.5 for RSA keys,
+ * ASN.1 for ECDSA keys) and the base64 encoded signature set in the Key-Signature header.
+ * Once renewed with a new key, the user is identified by the new key only and the certificates of the previous key
+ * are revoked, like with PUT /user/key.
+ * @apiName User - Renew certificate
+ * @apiGroup User
+ *
+ * @apiHeader {String} [Key-Signature] base64 signature of the certificate request by the current key
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X POST --cacert ca.crt --cert bob.crt --key bob.key -v "https://api.nebulo.io/user/certificate" --data-binary "@bob.csr"
+ *
+ * @apiSuccess (Success) {nothing} 201 Created
+ * @apiSuccessExample {binary} Success example
+ *		HTTP/1.1 201 "Created"
+ *		-----BEGIN CERTIFICATE-----
+		MIIE6jCCAtKgAwIBAgIBAjANBgkqhkiG9w0BAQsFADARMQ8wDQYDVQQDDAZuZWJ1
+		...
+		FG4WG+sgP5x/bNY5fZ4=
+		-----END CERTIFICATE-----
+ *
+ * @apiError (Errors 4XX) {json} 400 Bad Request: unable to load user certificate request or key signature
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate or key signature mismatch
+ * @apiError (Errors 4XX) {json} 409 Conflict: the new key is, or was, used by an user
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+*/
+func UserCertificateRenew(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	clientCSR, caCert, caPrivateKey, err := loadCertificate(c.Request().Body, c.Request().Header.Get("Content-Length"))
+	if err != nil {
+		return err
+	}
+	if err = clientCSR.CheckSignature(); err != nil {
+		return httperror.HTTPBadRequestError(fmt.Errorf("invalid certificate request signature: %v", err))
+	}
+
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(clientCSR.PublicKey)
+	if err != nil {
+		return httperror.HTTPInternalServerError(fmt.Errorf("unable to marshal public key: %v", err))
+	}
+	// a new key has to be approved by the current one
+	if !bytes.Equal(publicKeyDER, u.PublicKeyDER) {
+		return rotateKey(c, u, clientCSR, publicKeyDER, caCert, caPrivateKey)
+	}
+
+	clientCRT, err := signCertificate(clientCSR, caCert, caPrivateKey)
+	if err != nil {
+		return err
+	}
+	if _, err = registerCertificate(u, clientCRT); err != nil {
+		return err
+	}
+
+	return sendCertificate(c, clientCRT.Raw)
+}
+
+// checkKeySignature verify the base64 encoded signature of the data by the current key of the user
+func checkKeySignature(u *user.User, data []byte, signatureBase64 string) (err error) {
+	if signatureBase64 == "" {
+		return httperror.HTTPBadRequestError(fmt.Errorf("%s header is required to use a new key", headerKeySignature))
+	}
+	signature, err := base64.StdEncoding.DecodeString(signatureBase64)
+	if err != nil {
+		return httperror.HTTPBadRequestError(fmt.Errorf("unable to decode %s header: %v", headerKeySignature, err))
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(u.PublicKeyDER)
+	if err != nil {
+		return httperror.HTTPInternalServerError(fmt.Errorf("unable to parse user public key: %v", err))
+	}
+
+	digest := sha256.Sum256(data)
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) != nil {
+			return httperror.HTTPUnauthorizedError(errKeySignature)
+		}
+	case *ecdsa.PublicKey:
+		var sig struct{ R, S *big.Int }
+		if rest, err := asn1.Unmarshal(signature, &sig); err != nil || len(rest) != 0 || sig.R == nil || sig.S == nil {
+			return httperror.HTTPBadRequestError(fmt.Errorf("unable to parse %s header: malformed ecdsa signature", headerKeySignature))
+		}
+		if !ecdsa.Verify(key, digest[:], sig.R, sig.S) {
+			return httperror.HTTPUnauthorizedError(errKeySignature)
+		}
+	default:
+		return httperror.HTTPBadRequestError(fmt.Errorf("unsupported user public key type %T", publicKey))
+	}
+	return nil
+}
+
+// UserCertificateRevoke handle the route DELETE /user/certificate/:serial.
+// Revoke one of the certificates issued to the logged user
+/**
+ * @api {delete} /user/certificate/:serial Revoke a certificate
+ * @apiDescription Revoke one of the certificates issued to the user, for example the certificate of a lost device.
+ * The certificate is rejected right away and listed in the next certificate revocation list.
+ * @apiName User - Revoke certificate
+ * @apiGroup User
+ *
+ * @apiParam {String} serial hexadecimal serial number of the certificate
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X DELETE -v --cert bob.crt --key bob.key "https://api.nebulo.io/user/certificate/7b2c1f0e9d8a6b5c4d3e2f1a0b9c8d7e"
+ *
+ * @apiSuccess (Success) {nothing} 204 No content
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 204 "No Content"
+ *
+ * @apiError (Errors 4XX) {json} 400 Bad Request: malformed serial number or certificate already revoked
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate
+ * @apiError (Errors 4XX) {json} 404 Not found: certificate not found
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func UserCertificateRevoke(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	serial, err := certificate.ParseSerial(c.Param("serial"))
+	if err != nil {
+		return httperror.HTTPBadRequestError(err)
+	}
+	crt, err := certp.P.FindBySerial(serial)
+	if (err == nil && crt.UserID != u.ID) || err == certificate.ErrNotFound {
+		return httperror.HTTPNotFoundError(certificate.ErrNotFound)
+	} else if err != nil {
+		return httperror.HTTPInternalServerError(fmt.Errorf("unable to find certificate: %v", err))
+	}
+
+	if err = revokeCertificate(crt, time.Now()); err == certificate.ErrAlreadyRevoked {
+		return httperror.HTTPBadRequestError(err)
+	} else if err != nil {
+		return httperror.HTTPInternalServerError(fmt.Errorf("unable to revoke certificate: %v", err))
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// revokeCertificate revoke the certificate and forget the cached OCSP responses about it
+func revokeCertificate(crt *certificate.Certificate, when time.Time) (err error) {
+	err = certp.P.Revoke(crt, when)
+	if (err == nil || err == certificate.ErrAlreadyRevoked) && responder.R != nil {
+		responder.R.Evict(crt.Serial)
+	}
+	return err
+}
+
+// revokeCertificates revoke every valid certificates issued to the user, except the one with the given serial
+func revokeCertificates(u *user.User, except *big.Int) (err error) {
+	list, err := certp.P.ListByUser(*u)
+	if err != nil {
+		return fmt.Errorf("unable to list certificates: %v", err)
+	}
+
+	now := time.Now()
+	for _, crt := range list {
+		if crt.IsRevoked() || crt.IsExpired(now) || (except != nil && crt.Serial == certificate.FormatSerial(except)) {
+			continue
+		}
+		if err = revokeCertificate(crt, now); err != nil && err != certificate.ErrAlreadyRevoked {
+			return fmt.Errorf("unable to revoke certificate %s: %v", crt.Serial, err)
+		}
+	}
+	return nil
+}
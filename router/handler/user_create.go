@@ -9,13 +9,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"math/big"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/krostar/nebulo-golib/log"
 	"github.com/krostar/nebulo-golib/router/httperror"
 	"github.com/krostar/nebulo-golib/tools/cert"
+	"github.com/krostar/nebulo-server/certificate"
+	certp "github.com/krostar/nebulo-server/certificate/provider"
 	"github.com/krostar/nebulo-server/config"
 	"github.com/krostar/nebulo-server/user"
 	up "github.com/krostar/nebulo-server/user/provider"
@@ -45,12 +47,25 @@ import (
 		-----END CERTIFICATE-----
  *
  * @apiError (Errors 4XX) {json} 400 Bad Request: unable to load user certificate request
- * @apiError (Errors 4XX) {json} 409 Conflict: user already exist
+ * @apiError (Errors 4XX) {json} 409 Conflict: user already exist, or the key identified an user
  * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
 */
 func UserCreate(c echo.Context) (err error) {
-	// create client certificate template
-	clientCSR, clientCRTRaw, err := signCertificate(c.Request().Body, c.Request().Header.Get("Content-Length"))
+	// load required certificate
+	clientCSR, caCert, caPrivateKey, err := loadCertificate(c.Request().Body, c.Request().Header.Get("Content-Length"))
+	if err != nil {
+		return err
+	}
+
+	// check if a user exist with this public key
+	if _, err = up.P.FindByPublicKey(clientCSR.PublicKey); err == nil {
+		return httperror.UserExist()
+	} else if err != nil && err != user.ErrNotFound {
+		return httperror.HTTPInternalServerError(err)
+	}
+
+	// create client certificate
+	clientCRT, err := signCertificate(clientCSR, caCert, caPrivateKey)
 	if err != nil {
 		return err
 	}
@@ -64,13 +79,27 @@ func UserCreate(c echo.Context) (err error) {
 		PublicKeyDER: storablePublicKey,
 		FingerPrint:  cert.FingerprintSHA256(storablePublicKey),
 	}
-	if _, err = up.P.Create(newUser); err != nil {
+	if _, err = up.P.Create(newUser); err == user.ErrKeyUsed {
+		return httperror.UserExist()
+	} else if err != nil {
 		return httperror.HTTPInternalServerError(fmt.Errorf("unable to register user in user provider: %v", err))
 	}
+	if _, err = registerCertificate(newUser, clientCRT); err != nil {
+		// without the certificate the user can't connect, remove him so the registration can be retried
+		if errDelete := up.P.Delete(newUser); errDelete != nil {
+			log.Errorf("unable to remove user %d after a failed registration: %v", newUser.ID, errDelete)
+		}
+		return err
+	}
 
 	// send back the generated certificate
-	c.Response().WriteHeader(http.StatusCreated)
+	return sendCertificate(c, clientCRT.Raw)
+}
+
+// sendCertificate write the certificate as a pem encoded response
+func sendCertificate(c echo.Context, clientCRTRaw []byte) (err error) {
 	c.Response().Header().Add("Content-Type", "application/x-x509-user-cert")
+	c.Response().WriteHeader(http.StatusCreated)
 	err = pem.Encode(c.Response().Writer, &pem.Block{Type: "CERTIFICATE", Bytes: clientCRTRaw})
 	if err != nil {
 		return httperror.HTTPInternalServerError(fmt.Errorf("unable to send back the certificate: %v", err))
@@ -79,18 +108,11 @@ func UserCreate(c echo.Context) (err error) {
 	return nil
 }
 
-func signCertificate(requestBody io.Reader, contentLengthHeader string) (clientCSR *x509.CertificateRequest, clientCRTRaw []byte, err error) {
-	// load required certificate
-	clientCSR, caCert, caPrivateKey, err := loadCertificate(requestBody, contentLengthHeader)
+// signCertificate create a client certificate for the public key of the request, signed by the client CA
+func signCertificate(clientCSR *x509.CertificateRequest, caCert *x509.Certificate, caPrivateKey crypto.PrivateKey) (clientCRT *x509.Certificate, err error) {
+	serial, err := certificate.NewSerial()
 	if err != nil {
-		return nil, nil, err
-	}
-
-	// check if a user exist with this public key
-	if _, err = up.P.FindByPublicKey(clientCSR.PublicKey); err == nil {
-		return nil, nil, httperror.UserExist()
-	} else if err != nil && err != user.ErrNotFound {
-		return nil, nil, httperror.HTTPInternalServerError(err)
+		return nil, httperror.HTTPInternalServerError(err)
 	}
 
 	clientCRTTemplate := x509.Certificate{
@@ -98,7 +120,7 @@ func signCertificate(requestBody io.Reader, contentLengthHeader string) (clientC
 		SignatureAlgorithm: clientCSR.SignatureAlgorithm,
 		PublicKeyAlgorithm: clientCSR.PublicKeyAlgorithm,
 		PublicKey:          clientCSR.PublicKey,
-		SerialNumber:       big.NewInt(2),
+		SerialNumber:       serial,
 		Issuer:             caCert.Subject,
 		Subject:            clientCSR.Subject,
 		NotBefore:          time.Now(),
@@ -108,11 +130,22 @@ func signCertificate(requestBody io.Reader, contentLengthHeader string) (clientC
 	}
 
 	// create/sign the request with the client CA
-	clientCRTRaw, err = x509.CreateCertificate(rand.Reader, &clientCRTTemplate, caCert, clientCSR.PublicKey, caPrivateKey)
+	clientCRTRaw, err := x509.CreateCertificate(rand.Reader, &clientCRTTemplate, caCert, clientCSR.PublicKey, caPrivateKey)
 	if err != nil {
-		return nil, nil, httperror.HTTPInternalServerError(fmt.Errorf("unable to create certificate: %v", err))
+		return nil, httperror.HTTPInternalServerError(fmt.Errorf("unable to create certificate: %v", err))
+	}
+	if clientCRT, err = x509.ParseCertificate(clientCRTRaw); err != nil {
+		return nil, httperror.HTTPInternalServerError(fmt.Errorf("unable to parse created certificate: %v", err))
+	}
+	return clientCRT, nil
+}
+
+// registerCertificate keep track of a certificate issued to the user
+func registerCertificate(u *user.User, clientCRT *x509.Certificate) (crt *certificate.Certificate, err error) {
+	if crt, err = certp.P.Create(*u, clientCRT.SerialNumber, clientCRT.NotBefore, clientCRT.NotAfter); err != nil {
+		return nil, httperror.HTTPInternalServerError(fmt.Errorf("unable to register certificate: %v", err))
 	}
-	return clientCSR, clientCRTRaw, nil
+	return crt, nil
 }
 
 // get client certificate request from body
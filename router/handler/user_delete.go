@@ -4,15 +4,17 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/krostar/nebulo-golib/log"
 	"github.com/krostar/nebulo-golib/router/httperror"
 	"github.com/labstack/echo"
 
-	"github.com/krostar/nebulo-golib/tools/cert"
+	ap "github.com/krostar/nebulo-server/attachment/provider"
+	"github.com/krostar/nebulo-server/attachment/storage"
 	up "github.com/krostar/nebulo-server/user/provider"
 )
 
 // UserDelete handle the route DELETE /user/.
-// Delete all the users informations and revoke certificate
+// Delete all the users informations and revoke certificates
 /**
  * @api {delete} /user Delete user profile
  * @apiDescription Delete the user profile, wiping every data about the user.
@@ -36,13 +38,23 @@ func UserDelete(c echo.Context) (err error) {
 		return httperror.UserNotFound()
 	}
 
-	if err = cert.Revoke(c.Request().TLS.PeerCertificates[0]); err != nil {
-		return httperror.HTTPInternalServerError(fmt.Errorf("unable to revoke certificate: %v", err))
+	if err = revokeCertificates(u, nil); err != nil {
+		return httperror.HTTPInternalServerError(fmt.Errorf("unable to revoke certificates: %v", err))
+	}
+	// attachments contents are not stored in database, they are removed once the user is deleted
+	attachments, err := ap.P.ListByUser(*u)
+	if err != nil {
+		return httperror.HTTPInternalServerError(fmt.Errorf("unable to list user attachments: %v", err))
 	}
 	if err = up.P.Delete(u); err != nil {
 		return httperror.HTTPInternalServerError(fmt.Errorf("unable to delete user profile: %v", err))
 	}
+	for _, a := range attachments {
+		if err = storage.S.Delete(a.PublicID); err != nil {
+			log.Warningf("unable to delete attachment %q content: %v", a.PublicID, err)
+		}
+	}
 
-	// StatusAccepted because it may take some time for the certificate to be revoked everywhere
+	// StatusAccepted because the certificates are listed in the next certificate revocation list only
 	return c.NoContent(http.StatusAccepted)
 }
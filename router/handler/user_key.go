@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/krostar/nebulo-golib/log"
+	"github.com/krostar/nebulo-golib/router/httperror"
+	"github.com/krostar/nebulo-golib/tools/cert"
+	"github.com/labstack/echo"
+
+	"github.com/krostar/nebulo-server/user"
+	up "github.com/krostar/nebulo-server/user/provider"
+)
+
+var errSameKey = errors.New("certificate request is for the current key, see POST /user/certificate to renew the certificate")
+
+// UserKeyRotate handle the route PUT /user/key.
+// Move the logged user to a new key and return a CRT for it
+/**
+ * @api {put} /user/key Rotate the key
+ * @apiDescription Move the logged user to a new key, keeping the account, the channels and the messages.
+ * The certificate request is for the new key, its DER encoding must be signed with the current key (SHA-256,
+ * PKCS#1 v1.5 for RSA keys, ASN.1 for ECDSA keys) and the base64 encoded signature set in the Key-Signature header.
+ * Once rotated, the user is identified by the new key only, the certificates of the previous key are revoked
+ * and the previous key is kept in the key history; a key which identified an user can't be used again.
+ * @apiName User - Rotate key
+ * @apiGroup User
+ *
+ * @apiHeader {String} Key-Signature base64 signature of the certificate request by the current key
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X PUT --cacert ca.crt --cert bob.crt --key bob.key -H "Key-Signature: $(openssl dgst -sha256 -sign bob.key bob.csr.der | base64 -w0)" -v "https://api.nebulo.io/user/key" --data-binary "@bob-new.csr"
+ *
+ * @apiSuccess (Success) {nothing} 201 Created
+ * @apiSuccessExample {binary} Success example
+ *		HTTP/1.1 201 "Created"
+ *		-----BEGIN CERTIFICATE-----
+		MIIE6jCCAtKgAwIBAgIBAjANBgkqhkiG9w0BAQsFADARMQ8wDQYDVQQDDAZuZWJ1
+		...
+		FG4WG+sgP5x/bNY5fZ4=
+		-----END CERTIFICATE-----
+ *
+ * @apiError (Errors 4XX) {json} 400 Bad Request: unable to load user certificate request or key signature, or request for the current key
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate or key signature mismatch
+ * @apiError (Errors 4XX) {json} 409 Conflict: the new key is, or was, used by an user
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+*/
+func UserKeyRotate(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	clientCSR, caCert, caPrivateKey, err := loadCertificate(c.Request().Body, c.Request().Header.Get("Content-Length"))
+	if err != nil {
+		return err
+	}
+	if err = clientCSR.CheckSignature(); err != nil {
+		return httperror.HTTPBadRequestError(fmt.Errorf("invalid certificate request signature: %v", err))
+	}
+
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(clientCSR.PublicKey)
+	if err != nil {
+		return httperror.HTTPInternalServerError(fmt.Errorf("unable to marshal public key: %v", err))
+	}
+	if bytes.Equal(publicKeyDER, u.PublicKeyDER) {
+		return httperror.HTTPBadRequestError(errSameKey)
+	}
+
+	return rotateKey(c, u, clientCSR, publicKeyDER, caCert, caPrivateKey)
+}
+
+// rotateKey move the user to the new key of the certificate request, approved by the current key,
+// and send back the certificate of the new key
+func rotateKey(c echo.Context, u *user.User, clientCSR *x509.CertificateRequest, publicKeyDER []byte, caCert *x509.Certificate, caPrivateKey crypto.PrivateKey) (err error) {
+	if err = checkKeySignature(u, clientCSR.Raw, c.Request().Header.Get(headerKeySignature)); err != nil {
+		return err
+	}
+
+	clientCRT, err := signCertificate(clientCSR, caCert, caPrivateKey)
+	if err != nil {
+		return err
+	}
+
+	// the certificate is registered before the rotation, once rotated the user can only connect with it
+	crt, err := registerCertificate(u, clientCRT)
+	if err != nil {
+		return err
+	}
+	if err = up.P.RotateKey(u, publicKeyDER, cert.FingerprintSHA256(publicKeyDER), time.Now()); err != nil {
+		if errRevoke := revokeCertificate(crt, time.Now()); errRevoke != nil {
+			log.Errorf("unable to revoke certificate %s of a failed key rotation of user %d: %v", crt.Serial, u.ID, errRevoke)
+		}
+		if err == user.ErrKeyUsed {
+			return httperror.UserExist()
+		}
+		return httperror.HTTPInternalServerError(fmt.Errorf("unable to rotate user key: %v", err))
+	}
+
+	// certificates of the previous key can't authenticate the user anymore,
+	// failing to revoke them must not prevent the user to get the certificate of the new key
+	if err = revokeCertificates(u, clientCRT.SerialNumber); err != nil {
+		log.Warningf("unable to revoke certificates of the previous key of user %d: %v", u.ID, err)
+	}
+
+	return sendCertificate(c, clientCRT.Raw)
+}
+
+// UserKeysList handle the route GET /user/keys.
+// Return the key history of the logged user
+/**
+ * @api {get} /user/keys Get the key history
+ * @apiDescription List every keys which identified the logged user, ordered by addition; the current key is the one
+ * which is not retired.
+ * @apiName User - Key history
+ * @apiGroup User
+ *
+ * @apiExample {curl} Usage example
+ *		$>curl -X GET -v --cert bob.crt --key bob.key "https://api.nebulo.io/user/keys"
+ *
+ * @apiSuccess (Success) {nothing} 200 OK
+ * @apiSuccessExample {json} Success example
+ *		HTTP/1.1 200 "OK"
+ *		[
+ *		    {
+ *		        "key_fingerprint": "Yf8KqVvc6O8iQWz0jWGd8mQ9cLoFrz5BOU4Gjkv0Cjg",
+ *		        "added": "2017-05-02T09:12:41Z",
+ *		        "retired": "2017-06-14T18:03:12Z"
+ *		    },
+ *		    {
+ *		        "key_fingerprint": "3k1d6vQ2cS8pHnH4MwJ5YwI9xUzJpQ7rT0fWbE2aVgo",
+ *		        "added": "2017-06-14T18:03:12Z",
+ *		        "retired": null
+ *		    }
+ *		]
+ *
+ * @apiError (Errors 4XX) {json} 401 Unauthorized: missing client certificate
+ * @apiError (Errors 5XX) {json} 500 Internal server error: server failed to handle the request
+ */
+func UserKeysList(c echo.Context) (err error) {
+	u, err := GetLoggedUser(c.Get("user"))
+	if err != nil {
+		return httperror.UserNotFound()
+	}
+
+	keys, err := up.P.ListKeys(*u)
+	if err != nil {
+		return httperror.HTTPInternalServerError(fmt.Errorf("unable to list user keys: %v", err))
+	}
+
+	return c.JSONPretty(http.StatusOK, keys, "    ")
+}
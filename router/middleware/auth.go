@@ -8,6 +8,8 @@ import (
 	"github.com/krostar/nebulo-golib/tools/cert"
 	"github.com/labstack/echo"
 
+	"github.com/krostar/nebulo-server/certificate"
+	certp "github.com/krostar/nebulo-server/certificate/provider"
 	"github.com/krostar/nebulo-server/user"
 	up "github.com/krostar/nebulo-server/user/provider"
 )
@@ -15,6 +17,7 @@ import (
 var (
 	errCertificateNotProvider = errors.New("authentication certificate not provided")
 	errNoTLS                  = errors.New("authentication is based on TLS, without TLS authentication can't work")
+	errCertificateRevoked     = errors.New("certificate is revoked")
 )
 
 func mAuth(next echo.HandlerFunc, c echo.Context) (err error) {
@@ -30,12 +33,20 @@ func mAuth(next echo.HandlerFunc, c echo.Context) (err error) {
 
 	userCert := c.Request().TLS.PeerCertificates[0]
 
-	// check the certificate revokation
-	revoked, err := cert.VerifyCertificate(userCert)
-	if err != nil {
-		return httperror.HTTPUnauthorizedError(fmt.Errorf("unable to verify certificate: %v", err))
-	} else if revoked {
-		return httperror.HTTPUnauthorizedError(errors.New("certificate is revoked"))
+	// check the certificate revokation with the issued certificates registry,
+	// certificates issued before the registry existed are checked like they were before
+	status, err := certp.P.FindStatus(userCert.SerialNumber)
+	if err == certificate.ErrNotFound {
+		revoked, errVerify := cert.VerifyCertificate(userCert)
+		if errVerify != nil {
+			return httperror.HTTPUnauthorizedError(fmt.Errorf("unable to verify certificate: %v", errVerify))
+		} else if revoked {
+			return httperror.HTTPUnauthorizedError(errCertificateRevoked)
+		}
+	} else if err != nil {
+		return httperror.HTTPInternalServerError(fmt.Errorf("unable to verify certificate: %v", err))
+	} else if status == certificate.StatusRevoked {
+		return httperror.HTTPUnauthorizedError(errCertificateRevoked)
 	}
 
 	c.Set("userCert", userCert)
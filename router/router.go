@@ -86,6 +86,14 @@ func setupMiddlewares() {
 func setupRoutes() {
 	router.GET("/version", handler.Version)
 
+	// domain/crl
+	router.GET("/crl", handler.CRL)        //get the certificate revocation list, DER encoded
+	router.GET("/crl.pem", handler.CRLPEM) //get the certificate revocation list, PEM encoded
+
+	// domain/ocsp
+	router.POST("/ocsp", handler.OCSPPost) //get the status of a certificate
+	router.GET("/ocsp/*", handler.OCSPGet) //get the status of a certificate, cacheable
+
 	// domain/user/...
 	user := router.Group("/user")
 	user.GET("", handler.UserInfos, puMdw["auth"])     //user profile infos
@@ -93,28 +101,57 @@ func setupRoutes() {
 	user.PUT("", handler.UserEdit, puMdw["auth"])      //edit user profile
 	user.DELETE("", handler.UserDelete, puMdw["auth"]) //delete user profile
 
+	// domain/user/certificate
+	user.POST("/certificate", handler.UserCertificateRenew, puMdw["auth"])            //renew user certificate before it expires
+	user.DELETE("/certificate/:serial", handler.UserCertificateRevoke, puMdw["auth"]) //revoke one of the user certificates
+
+	// domain/user/key
+	user.PUT("/key", handler.UserKeyRotate, puMdw["auth"]) //move the user to a new key
+	user.GET("/keys", handler.UserKeysList, puMdw["auth"]) //list the keys which identified the user
+
 	// domain/chans
 	router.GET("/chans", handler.ChansList, puMdw["auth"]) //list all channels
 
+	// domain/ws
+	router.GET("/ws", handler.EventsWebSocket, puMdw["auth"]) //push real-time events through a websocket
+
 	// domain/chan/...
 	// identified users are required to make these calls
 	//     that's why everything using channel group use auth middleware
 	channel := router.Group("/chan", puMdw["auth"])
-	// channel.GET("/:chan", handler.ChanInfos) //get info for a specific channel
-	channel.POST("", handler.ChanCreate) //add a new channel
-	// channel.PUT("/:chan", handler.ChanEdit)      //edit info of a specific channel
-	// channel.DELETE("/:chan", handler.ChanDelete) //delete a specific channel
+	channel.GET("/:chan", handler.ChanInfos)     //get info for a specific channel
+	channel.POST("", handler.ChanCreate)         //add a new channel
+	channel.GET("/events", handler.ChanEvents)   //stream events with server-sent events
+	channel.PUT("/:chan", handler.ChanEdit)      //edit info of a specific channel
+	channel.DELETE("/:chan", handler.ChanDelete) //delete a specific channel
+
+	// domain/chan/invitations and domain/chan/:chan/{members,membership}
+	channel.GET("/invitations", handler.ChanInvitationsList) //list pending invitations
+	channel.POST("/:chan/members", handler.ChanInvite)       //invite an user in a specific channel
+	channel.PUT("/:chan/membership", handler.ChanJoin)       //accept an invitation
+	channel.DELETE("/:chan/membership", handler.ChanLeave)   //decline an invitation or leave a channel
 
 	// domain/chan/:chan/messages/...
 	messages := channel.Group("/:chan/messages")
-	messages.GET("", handler.ChanMessagesList) //get message list for a specific channel
-	// messages.DELETE("/", handler.ChanMessagesDelete) //delete range of messages
+	messages.GET("", handler.ChanMessagesList)      //get message list for a specific channel
+	messages.DELETE("", handler.ChanMessagesDelete) //delete range of messages
+	messages.PUT("/seen", handler.ChanMessagesSeen) //mark messages as seen
+	messages.PUT("/ack", handler.ChanMessagesAck)   //acknowledge messages reception
 	//
 	// domain/chan/:chan/message/...
 	message := channel.Group("/:chan/message")
-	message.POST("", handler.ChanMessageCreate) //add message to a specific channel
-	// message.PUT("/:message", handler.ChanMessageEdit)      //edit a specific message
-	// message.DELETE("/:message", handler.ChanMessa && config.Config.TLSKeyFile != ""geDelete) //delete a specific message
+	message.POST("", handler.ChanMessageCreate)                    //add message to a specific channel
+	message.PUT("/:message", handler.ChanMessageEdit)              //edit a specific message
+	message.DELETE("/:message", handler.ChanMessageDelete)         //delete a specific message
+	message.GET("/:message/receipts", handler.ChanMessageReceipts) //get delivery and read state of a specific message
+	//
+	// domain/chan/:chan/attachment/...
+	attachment := channel.Group("/:chan/attachment")
+	attachment.POST("", handler.ChanAttachmentCreate)                 //register a new attachment
+	attachment.PATCH("/:attachment", handler.ChanAttachmentUpload)    //upload a chunk of a specific attachment
+	attachment.GET("/:attachment", handler.ChanAttachmentDownload)    //download a specific attachment
+	attachment.GET("/:attachment/infos", handler.ChanAttachmentInfos) //get info for a specific attachment
+	attachment.DELETE("/:attachment", handler.ChanAttachmentDelete)   //delete a specific attachment
 }
 
 func run(environment *env.Config, tlsConfig *tls.Config) error {
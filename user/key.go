@@ -0,0 +1,24 @@
+package user
+
+import "time"
+
+// Key is a public key which identify, or identified, an user; the key history of an user
+// is kept to audit the key rotations, a key identify at most one user forever
+type Key struct {
+	ID           int        `json:"-" gorm:"column:id; primary_key; not null"`
+	UserID       int        `json:"-" gorm:"column:user_id; not null"`
+	PublicKeyDER []byte     `json:"-" gorm:"column:key_public_der; size:2000; not null"`
+	FingerPrint  string     `json:"key_fingerprint" gorm:"column:key_fingerprint; size:51; not null"`
+	Added        time.Time  `json:"added" gorm:"column:added; not null" sql:"DEFAULT:current_timestamp"`
+	Retired      *time.Time `json:"retired" gorm:"column:retired" sql:"DEFAULT:NULL"`
+}
+
+// TableName is the table name in database
+func (k *Key) TableName() string {
+	return "user_keys"
+}
+
+// IsRetired return true if the key does not identify the user anymore
+func (k *Key) IsRetired() bool {
+	return k.Retired != nil
+}
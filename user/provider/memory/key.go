@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/krostar/nebulo-server/user"
+)
+
+// RotateKey replace the key which identify the user, the previous key is retired since the given date
+func (p *Provider) RotateKey(u *user.User, publicKeyDER []byte, fingerprint string, when time.Time) (err error) {
+	if u == nil {
+		return user.ErrNil
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	if p.keyUsed(publicKeyDER) {
+		return user.ErrKeyUsed
+	}
+	i := p.index(u.ID)
+	if i < 0 {
+		return user.ErrNotFound
+	}
+
+	when = when.UTC()
+	p.Users[i].PublicKeyDER, p.Users[i].FingerPrint = publicKeyDER, fingerprint
+	for j := range p.UserKeys {
+		if p.UserKeys[j].UserID == u.ID && !p.UserKeys[j].IsRetired() {
+			retired := when
+			p.UserKeys[j].Retired = &retired
+		}
+	}
+	p.UserKeys = append(p.UserKeys, user.Key{
+		ID:           p.NextID("user_keys"),
+		UserID:       u.ID,
+		PublicKeyDER: publicKeyDER,
+		FingerPrint:  fingerprint,
+		Added:        when,
+	})
+
+	u.PublicKeyDER, u.FingerPrint = publicKeyDER, fingerprint
+	return nil
+}
+
+// ListKeys return every keys which identified the user, ordered by addition
+func (p *Provider) ListKeys(u user.User) (keys []*user.Key, err error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	keys = []*user.Key{}
+	for _, existing := range p.UserKeys {
+		if existing.UserID == u.ID {
+			k := existing
+			keys = append(keys, &k)
+		}
+	}
+	return keys, nil
+}
+
+// keyUsed return true if the key identify, or identified, an user, it must be called with the lock held
+func (p *Provider) keyUsed(publicKeyDER []byte) bool {
+	for _, existing := range p.UserKeys {
+		if bytes.Equal(existing.PublicKeyDER, publicKeyDER) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package memory
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/krostar/nebulo-server/provider/memory"
+	"github.com/krostar/nebulo-server/user"
+	"github.com/krostar/nebulo-server/user/provider"
+)
+
+// Provider implements the methods needed to manage users
+// with every rows kept in memory
+type Provider struct {
+	*memory.Store
+	provider.Provider
+}
+
+// Init initialize a memory provider and set it as the used provider
+func Init() error {
+	if memory.S == nil {
+		return memory.ErrStoreIsNil
+	}
+
+	provider.P = &Provider{Store: memory.S}
+	return nil
+}
+
+// Login update field on user login
+func (p *Provider) Login(u *user.User) (err error) {
+	if u == nil {
+		return user.ErrNil
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	now := time.Now().UTC()
+	u.LoginLast = now
+	if u.LoginFirst.UTC() == time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC) || u.LoginFirst.IsZero() {
+		u.LoginFirst = now
+	}
+	if i := p.index(u.ID); i >= 0 {
+		p.Users[i].LoginFirst, p.Users[i].LoginLast = u.LoginFirst, u.LoginLast
+	}
+
+	return nil
+}
+
+// Create a new user
+func (p *Provider) Create(userToAdd *user.User) (u *user.User, err error) {
+	if userToAdd == nil {
+		return nil, user.ErrNil
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	// check if the key identify, or identified, an user
+	if p.keyUsed(userToAdd.PublicKeyDER) {
+		return nil, user.ErrKeyUsed
+	}
+
+	// same default values as the sql columns
+	epoch := time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if userToAdd.Signup.IsZero() {
+		userToAdd.Signup = time.Now().UTC()
+	}
+	if userToAdd.LoginFirst.IsZero() {
+		userToAdd.LoginFirst = epoch
+	}
+	if userToAdd.LoginLast.IsZero() {
+		userToAdd.LoginLast = epoch
+	}
+
+	userToAdd.ID = p.NextID("users")
+	p.Users = append(p.Users, *userToAdd)
+	p.UserKeys = append(p.UserKeys, user.Key{
+		ID:           p.NextID("user_keys"),
+		UserID:       userToAdd.ID,
+		PublicKeyDER: userToAdd.PublicKeyDER,
+		FingerPrint:  userToAdd.FingerPrint,
+		Added:        userToAdd.Signup.UTC(),
+	})
+
+	u = userToAdd
+	return u, nil
+}
+
+// Delete a existing user
+func (p *Provider) Delete(u *user.User) (err error) {
+	if u == nil {
+		return user.ErrNil
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	if p.index(u.ID) < 0 {
+		return user.ErrNotFound
+	}
+	p.DeleteUser(u.ID)
+
+	return nil
+}
+
+// FindByPublicKey is used to find a user from his public key
+func (p *Provider) FindByPublicKey(publicKey interface{}) (u *user.User, err error) {
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal public key: %v", err)
+	}
+	return p.FindByPublicKeyDER(publicKeyDER)
+}
+
+// FindByPublicKeyDERBase64 is used to find a user from his public key der base64 formatted
+func (p *Provider) FindByPublicKeyDERBase64(publicKeyDERBase64 string) (u *user.User, err error) {
+	publicKeyDER, err := base64.StdEncoding.DecodeString(publicKeyDERBase64)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode base64 public key der: %v", err)
+	}
+	return p.FindByPublicKeyDER(publicKeyDER)
+}
+
+// FindByPublicKeyDER is used to find a user from his public key der formatted
+func (p *Provider) FindByPublicKeyDER(publicKeyDER []byte) (u *user.User, err error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	for _, existing := range p.Users {
+		if bytes.Equal(existing.PublicKeyDER, publicKeyDER) {
+			found := existing
+			return &found, nil
+		}
+	}
+	return nil, user.ErrNotFound
+}
+
+// FindByID is used to find a user from his ID
+func (p *Provider) FindByID(id int) (u *user.User, err error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	i := p.index(id)
+	if i < 0 {
+		return nil, user.ErrNotFound
+	}
+	found := p.Users[i]
+	return &found, nil
+}
+
+// Update only fiew fields from user
+func (p *Provider) Update(u *user.User, fields map[string]interface{}) (err error) {
+	if u == nil {
+		return user.ErrNil
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	if err = memory.SetColumns(u, fields); err != nil {
+		return fmt.Errorf("unable to update user informations: %v", err)
+	}
+	if i := p.index(u.ID); i >= 0 {
+		if err = memory.SetColumns(&p.Users[i], fields); err != nil {
+			return fmt.Errorf("unable to update user informations: %v", err)
+		}
+	}
+	return nil
+}
+
+// index return the position of the user in the store, or -1
+func (p *Provider) index(id int) int {
+	for i, u := range p.Users {
+		if u.ID == id {
+			return i
+		}
+	}
+	return -1
+}
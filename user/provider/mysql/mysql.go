@@ -2,7 +2,6 @@ package sqlite
 
 import (
 	gp "github.com/krostar/nebulo-golib/provider"
-	"github.com/krostar/nebulo-server/user"
 	"github.com/krostar/nebulo-server/user/provider"
 	dp "github.com/krostar/nebulo-server/user/provider/sql"
 )
@@ -25,17 +24,3 @@ func Init() error {
 	provider.P = p
 	return nil
 }
-
-// DropTables delete all the channels tables
-func (p *Provider) DropTables() (err error) {
-	u := &user.User{}
-
-	err = gp.RP.DB.Exec("SET FOREIGN_KEY_CHECKS=0;").Error
-	if err == nil {
-		err = p.DB.DropTableIfExists(u).Error
-	}
-	if err == nil {
-		err = gp.RP.DB.Exec("SET FOREIGN_KEY_CHECKS=1;").Error
-	}
-	return err
-}
@@ -1,14 +1,13 @@
 package provider
 
 import (
-	gp "github.com/krostar/nebulo-golib/provider"
+	"time"
+
 	"github.com/krostar/nebulo-server/user"
 )
 
 // Provider contains all the methods needed to manage users
 type Provider interface {
-	gp.TablesManagement
-
 	Login(u *user.User) (err error)
 	Create(userToAdd *user.User) (u *user.User, err error)
 	Delete(u *user.User) (err error)
@@ -19,6 +18,9 @@ type Provider interface {
 	FindByID(ID int) (u *user.User, err error)
 
 	Update(u *user.User, fields map[string]interface{}) (err error)
+
+	RotateKey(u *user.User, publicKeyDER []byte, fingerprint string, when time.Time) (err error)
+	ListKeys(u user.User) (keys []*user.Key, err error)
 }
 
 // P is the selected provider
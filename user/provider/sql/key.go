@@ -0,0 +1,86 @@
+package sql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/krostar/nebulo-server/user"
+)
+
+// RotateKey replace the key which identify the user, the previous key is retired since the given date
+func (p *Provider) RotateKey(u *user.User, publicKeyDER []byte, fingerprint string, when time.Time) (err error) {
+	if u == nil {
+		return user.ErrNil
+	}
+	when = when.UTC()
+
+	tx := p.DB.Begin()
+	if err = tx.Error; err != nil {
+		return fmt.Errorf("unable to start transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	used, err := keyUsed(tx, publicKeyDER)
+	if err != nil {
+		return err
+	} else if used {
+		return user.ErrKeyUsed
+	}
+
+	query := tx.Model(&user.User{}).Where("id = ?", u.ID).Updates(map[string]interface{}{
+		"key_public_der":  publicKeyDER,
+		"key_fingerprint": fingerprint,
+	})
+	if err = query.Error; err != nil {
+		return p.keyConflict(tx, publicKeyDER, fmt.Errorf("unable to update user key: %v", err))
+	} else if query.RowsAffected == 0 {
+		return user.ErrNotFound
+	}
+	if err = tx.Model(&user.Key{}).Where("user_id = ? AND retired IS NULL", u.ID).Update("retired", when).Error; err != nil {
+		return fmt.Errorf("unable to retire previous key: %v", err)
+	}
+	if err = tx.Create(&user.Key{UserID: u.ID, PublicKeyDER: publicKeyDER, FingerPrint: fingerprint, Added: when}).Error; err != nil {
+		return p.keyConflict(tx, publicKeyDER, fmt.Errorf("unable to insert key: %v", err))
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		return fmt.Errorf("unable to commit key rotation: %v", err)
+	}
+
+	u.PublicKeyDER, u.FingerPrint = publicKeyDER, fingerprint
+	return nil
+}
+
+// ListKeys return every keys which identified the user, ordered by addition
+func (p *Provider) ListKeys(u user.User) (keys []*user.Key, err error) {
+	keys = []*user.Key{}
+	if err = p.DB.Where("user_id = ?", u.ID).Order("id").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("unable to select keys in db: %v", err)
+	}
+	return keys, nil
+}
+
+// keyUsed return true if the key identify, or identified, an user
+func keyUsed(db *gorm.DB, publicKeyDER []byte) (used bool, err error) {
+	var count int
+	if err = db.Model(&user.Key{}).Where("key_public_der = ?", publicKeyDER).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("unable to count keys in db: %v", err)
+	}
+	return count > 0, nil
+}
+
+// keyConflict return user.ErrKeyUsed instead of err when the write failed because a concurrent
+// transaction used the same key, the transaction is rolled back to look at the committed keys
+func (p *Provider) keyConflict(tx *gorm.DB, publicKeyDER []byte, err error) error {
+	tx.Rollback()
+	if used, errUsed := keyUsed(p.DB, publicKeyDER); errUsed == nil && used {
+		return user.ErrKeyUsed
+	}
+	return err
+}
@@ -1,11 +1,11 @@
 package sql
 
 import (
-	"errors"
 	"fmt"
 	"time"
 
 	gp "github.com/krostar/nebulo-golib/provider"
+	"github.com/krostar/nebulo-server/attachment"
 	"github.com/krostar/nebulo-server/channel/provider"
 	"github.com/krostar/nebulo-server/user"
 	"github.com/labstack/gommon/log"
@@ -44,17 +44,42 @@ func (p *Provider) Create(userToAdd *user.User) (u *user.User, err error) {
 		return nil, user.ErrNil
 	}
 
-	// check if user exist
-	_, err = p.FindByPublicKeyDER(userToAdd.PublicKeyDER)
-	if err != nil && err != user.ErrNotFound {
-		return nil, fmt.Errorf("unable to find user: %v", err)
+	tx := p.DB.Begin()
+	if err = tx.Error; err != nil {
+		return nil, fmt.Errorf("unable to start transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// check if the key identify, or identified, an user
+	used, err := keyUsed(tx, userToAdd.PublicKeyDER)
+	if err != nil {
+		return nil, err
+	} else if used {
+		return nil, user.ErrKeyUsed
+	}
+
+	if err = tx.Create(userToAdd).Error; err != nil {
+		return nil, p.keyConflict(tx, userToAdd.PublicKeyDER, fmt.Errorf("unable to insert user: %v", err))
+	}
+	added := userToAdd.Signup
+	if added.IsZero() {
+		added = time.Now()
 	}
-	if err != user.ErrNotFound {
-		return nil, errors.New("an user already exist with this public key")
+	if err = tx.Create(&user.Key{
+		UserID:       userToAdd.ID,
+		PublicKeyDER: userToAdd.PublicKeyDER,
+		FingerPrint:  userToAdd.FingerPrint,
+		Added:        added.UTC(),
+	}).Error; err != nil {
+		return nil, p.keyConflict(tx, userToAdd.PublicKeyDER, fmt.Errorf("unable to insert user key: %v", err))
 	}
 
-	if err = p.DB.Create(userToAdd).Error; err != nil {
-		return nil, fmt.Errorf("unable to insert user: %v", err)
+	if err = tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("unable to commit user creation: %v", err)
 	}
 
 	u = userToAdd
@@ -75,10 +100,32 @@ func (p *Provider) Delete(u *user.User) (err error) {
 		return user.ErrNotFound
 	}
 
-	if err = p.DB.Delete(u).Error; err != nil {
+	tx := p.DB.Begin()
+	if err = tx.Error; err != nil {
+		return fmt.Errorf("unable to start transaction: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// the keys and the attachments are deleted even without foreign keys, like on SQLite
+	if err = tx.Where("user_id = ?", u.ID).Delete(&user.Key{}).Error; err != nil {
+		return fmt.Errorf("unable to delete user keys: %v", err)
+	}
+	if err = tx.Where("owner_id = ? OR channel_id IN (SELECT id FROM channels WHERE creator_id = ?)", u.ID, u.ID).
+		Delete(&attachment.Attachment{}).Error; err != nil {
+		return fmt.Errorf("unable to delete user attachments: %v", err)
+	}
+	if err = tx.Delete(u).Error; err != nil {
 		return fmt.Errorf("unable to delete user: %v", err)
 	}
 
+	if err = tx.Commit().Error; err != nil {
+		return fmt.Errorf("unable to commit user deletion: %v", err)
+	}
+
 	return nil
 }
 
@@ -14,6 +14,8 @@ var (
 	ErrNotFound = errors.New("user not found")
 	// ErrNil is throw when an user is nil
 	ErrNil = errors.New("user is nil")
+	// ErrKeyUsed is throw when a public key identify, or identified, an user
+	ErrKeyUsed = errors.New("public key is already used")
 )
 
 // User is the modelisation of an user